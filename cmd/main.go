@@ -1,9 +1,13 @@
 package main
 
 import (
+	"os"
+
 	"github.com/algolia/fake-insights-generator/pkg/cmd"
 )
 
 func main() {
-	cmd.NewRootCmd().Execute()
+	if err := cmd.NewRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
 }
@@ -2,14 +2,13 @@ package function
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"io/ioutil"
 	"os"
 
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/personalization"
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
 	"github.com/algolia/fake-insights-generator/pkg/cmd"
+	"github.com/algolia/fake-insights-generator/pkg/snapshot"
+	"github.com/algolia/fake-insights-generator/pkg/utils"
 )
 
 // PubSubMessage is the payload of a Pub/Sub event.
@@ -20,6 +19,10 @@ type PubSubMessage struct {
 }
 
 func PopulateInsights(ctx context.Context, m PubSubMessage) error {
+	// Only /tmp is writable in a Cloud Function
+	if os.Getenv("EVENTS_RECORD_TOKENS") == "" {
+		os.Setenv("EVENTS_RECORD_TOKENS", "/tmp/user-tokens.txt")
+	}
 	cmdEvents := cmd.NewEventsCmd()
 	err := cmdEvents.Execute()
 	if err != nil {
@@ -33,60 +36,27 @@ func RestoreSettingsAndPersoStrategy(ctx context.Context, m PubSubMessage) error
 	apiKey := os.Getenv("ALGOLIA_API_KEY")
 	indexName := os.Getenv("ALGOLIA_INDEX_NAME")
 
-	client := search.NewClient(appId, apiKey)
-	index := client.InitIndex(indexName)
-
-	// Restore the settings
-	settingsFile := os.Getenv("ECOM_SETTINGS")
-	settings, err := os.Open(settingsFile)
-	if err != nil {
-		return err
+	// The personalization strategy region, US if not set
+	regionName := os.Getenv("ALGOLIA_REGION")
+	if regionName == "" {
+		regionName = "us"
 	}
-	byteValue, _ := ioutil.ReadAll(settings)
-	var searchSettings search.Settings
-	err = json.Unmarshal(byteValue, &searchSettings)
+	_, persoRegion, err := utils.APIRegions(regionName)
 	if err != nil {
 		return err
 	}
 
-	_, err = index.SetSettings(searchSettings)
-	if err != nil {
-		return errors.New("Error while restoring settings: " + err.Error())
-	}
-
-	// Restore the rules
-	rulesFile := os.Getenv("ECOM_RULES")
-	rules, err := os.Open(rulesFile)
-	if err != nil {
-		return err
-	}
-	byteValue, _ = ioutil.ReadAll(rules)
-	var rulesList []search.Rule
-	err = json.Unmarshal(byteValue, &rulesList)
-	if err != nil {
-		return err
-	}
-
-	_, err = index.SaveRules(rulesList)
-	if err != nil {
-		return errors.New("Error while restoring rules: " + err.Error())
-	}
+	client := search.NewClient(appId, apiKey)
+	index := client.InitIndex(indexName)
+	persoClient := personalization.NewClient(appId, apiKey, persoRegion)
 
-	// Restore the personalization profile
-	persoStrategyFile := os.Getenv("ECOM_PERSO_STRATEGY")
-	persoStrategy, err := os.Open(persoStrategyFile)
+	// Restore the settings, the rules, the synonyms (if set) and the personalization strategy.
+	// The rules and synonyms are upserted: the other ones of the index are kept.
+	s := &snapshot.Snapshot{}
+	err = snapshot.LoadSections(s, os.Getenv("ECOM_SETTINGS"), os.Getenv("ECOM_RULES"),
+		os.Getenv("ECOM_SYNONYMS"), os.Getenv("ECOM_PERSO_STRATEGY"))
 	if err != nil {
 		return err
 	}
-	byteValue, _ = ioutil.ReadAll(persoStrategy)
-	var persoStrategyContent personalization.Strategy
-	err = json.Unmarshal(byteValue, &persoStrategyContent)
-	if err != nil {
-		return errors.New("Error while restoring personalization strategy: " + err.Error())
-	}
-
-	persoClient := personalization.NewClient(appId, apiKey, "US")
-	persoClient.SetPersonalizationStrategy(persoStrategyContent)
-
-	return nil
+	return s.Restore(index, persoClient, snapshot.Upsert)
 }
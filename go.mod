@@ -9,22 +9,22 @@ require (
 	github.com/bxcodec/faker/v3 v3.6.0 // indirect
 	github.com/cli/safeexec v1.0.0
 	github.com/fatih/color v1.13.0 // indirect
-	github.com/go-gota/gota v0.12.0 // indirect
+	github.com/go-gota/gota v0.12.0
 	github.com/gocarina/gocsv v0.0.0-20211020200912-82fc2684cc48
 	github.com/google/shlex v0.0.0-20191202100458-e7afc7fbc510
-	github.com/google/uuid v1.3.0 // indirect
+	github.com/google/uuid v1.3.0
 	github.com/joho/godotenv v1.4.0
 	github.com/mattn/go-colorable v0.1.12
 	github.com/mattn/go-isatty v0.0.14
 	github.com/mgutz/ansi v0.0.0-20200706080929-d51e80ef957d
-	github.com/montanaflynn/stats v0.6.6
 	github.com/mroth/weightedrand v0.4.1
 	github.com/muesli/reflow v0.3.0
 	github.com/muesli/termenv v0.9.0
 	github.com/spf13/cobra v1.2.1
-	github.com/spf13/pflag v1.0.5 // indirect
-	github.com/spf13/viper v1.9.0 // indirect
+	github.com/spf13/pflag v1.0.5
+	github.com/spf13/viper v1.9.0
 	golang.org/x/net v0.0.0-20211208012354-db4efeb81f4b // indirect
 	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
 	gonum.org/v1/gonum v0.9.3 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,207 @@
+// Package cleanup deletes the data of the fake users (their events and personalization profiles),
+// from the user tokens recorded by the events command.
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/algolia/fake-insights-generator/pkg/utils"
+)
+
+// ErrNotFound is returned by a Deleter when the user token has no data.
+var ErrNotFound = errors.New("user token not found")
+
+// Deleter deletes the data of a user token.
+type Deleter interface {
+	Name() string
+	DeleteUserToken(ctx context.Context, userToken string) error
+}
+
+// APIDeleter deletes the data of the user tokens with an Algolia API endpoint.
+type APIDeleter struct {
+	name string
+
+	Client *http.Client
+	// BaseURL is the scheme and host of the API, e.g. https://insights.us.algolia.io.
+	BaseURL string
+	// Path is the path of a user token, with a %s verb for the token.
+	Path string
+
+	AppID  string
+	APIKey string
+}
+
+// NewInsightsDeleter returns a Deleter of the events of the user tokens, in the Insights API.
+func NewInsightsDeleter(appID, apiKey, region string) (*APIDeleter, error) {
+	insights, _, err := utils.APIRegions(region)
+	if err != nil {
+		return nil, err
+	}
+	return &APIDeleter{
+		name:    "insights",
+		Client:  http.DefaultClient,
+		BaseURL: fmt.Sprintf("https://insights.%s.algolia.io", insights),
+		Path:    "/1/usertokens/%s",
+		AppID:   appID,
+		APIKey:  apiKey,
+	}, nil
+}
+
+// NewPersonalizationDeleter returns a Deleter of the personalization profiles of the user tokens.
+func NewPersonalizationDeleter(appID, apiKey, region string) (*APIDeleter, error) {
+	_, personalization, err := utils.APIRegions(region)
+	if err != nil {
+		return nil, err
+	}
+	return &APIDeleter{
+		name:    "personalization",
+		Client:  http.DefaultClient,
+		BaseURL: fmt.Sprintf("https://personalization.%s.algolia.com", personalization),
+		Path:    "/1/profiles/%s",
+		AppID:   appID,
+		APIKey:  apiKey,
+	}, nil
+}
+
+func (d *APIDeleter) Name() string {
+	return d.name
+}
+
+func (d *APIDeleter) DeleteUserToken(ctx context.Context, userToken string) error {
+	u := d.BaseURL + fmt.Sprintf(d.Path, url.PathEscape(userToken))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Algolia-Application-Id", d.AppID)
+	req.Header.Set("X-Algolia-API-Key", d.APIKey)
+
+	res, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case res.StatusCode >= 300:
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s API error [%d] %s", d.name, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// LocalDeleter is a stand-in Deleter for the tests, recording the user tokens instead of deleting their data.
+type LocalDeleter struct {
+	Label string
+	// Errors are the errors returned for some user tokens.
+	Errors map[string]error
+
+	mu      sync.Mutex
+	Deleted []string
+}
+
+func (d *LocalDeleter) Name() string {
+	return d.Label
+}
+
+func (d *LocalDeleter) DeleteUserToken(ctx context.Context, userToken string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := d.Errors[userToken]; err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Deleted = append(d.Deleted, userToken)
+	return nil
+}
+
+// Result is the outcome of the cleanup for a Deleter.
+type Result struct {
+	Deleted  int
+	NotFound int
+	Failed   int
+	// LastError is the last error of the failed deletions.
+	LastError error
+}
+
+// Report is the outcome of a cleanup.
+type Report struct {
+	// Results are the results of each Deleter, by name.
+	Results map[string]*Result
+	// Remaining are the user tokens with a failed deletion, to retry.
+	Remaining []string
+}
+
+// Run deletes the data of the user tokens with every Deleter, with concurrent workers.
+// A token whose data is not found is considered deleted. The cleanup stops when the context is
+// canceled, the tokens not processed yet being remaining.
+func Run(ctx context.Context, tokens []string, concurrency int, deleters ...Deleter) *Report {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	report := &Report{Results: make(map[string]*Result)}
+	for _, d := range deleters {
+		report.Results[d.Name()] = &Result{}
+	}
+
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	ch := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for token := range ch {
+				for _, d := range deleters {
+					err := d.DeleteUserToken(ctx, token)
+					mu.Lock()
+					result := report.Results[d.Name()]
+					switch {
+					case err == nil:
+						result.Deleted++
+					case errors.Is(err, ErrNotFound):
+						result.NotFound++
+					default:
+						result.Failed++
+						result.LastError = err
+						failed[token] = true
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	sent := 0
+send:
+	for _, token := range tokens {
+		select {
+		case ch <- token:
+			sent++
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	for i, token := range tokens {
+		if i >= sent || failed[token] {
+			report.Remaining = append(report.Remaining, token)
+		}
+	}
+	return report
+}
@@ -0,0 +1,113 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	insights := &LocalDeleter{Label: "insights", Errors: map[string]error{"user-3": ErrNotFound}}
+	perso := &LocalDeleter{Label: "personalization", Errors: map[string]error{"user-2": errors.New("rate limited")}}
+
+	tokens := []string{"user-1", "user-2", "user-3"}
+	report := Run(context.Background(), tokens, 2, insights, perso)
+
+	if r := report.Results["insights"]; r.Deleted != 2 || r.NotFound != 1 || r.Failed != 0 {
+		t.Errorf("unexpected insights result: %+v", r)
+	}
+	if r := report.Results["personalization"]; r.Deleted != 2 || r.Failed != 1 || r.LastError == nil {
+		t.Errorf("unexpected personalization result: %+v", r)
+	}
+	// The token with a failed deletion is kept to retry, the one not found is done.
+	if expected := []string{"user-2"}; !reflect.DeepEqual(report.Remaining, expected) {
+		t.Errorf("Remaining = %v, want %v", report.Remaining, expected)
+	}
+
+	sort.Strings(insights.Deleted)
+	if expected := []string{"user-1", "user-2"}; !reflect.DeepEqual(insights.Deleted, expected) {
+		t.Errorf("insights deleted %v, want %v", insights.Deleted, expected)
+	}
+}
+
+func TestRun_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tokens := []string{"user-1", "user-2"}
+	report := Run(ctx, tokens, 1, &LocalDeleter{Label: "insights"})
+	if !reflect.DeepEqual(report.Remaining, tokens) {
+		t.Errorf("Remaining = %v, want %v", report.Remaining, tokens)
+	}
+}
+
+func TestAPIDeleter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.Header.Get("X-Algolia-Application-Id") != "app" || r.Header.Get("X-Algolia-API-Key") != "key" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/1/profiles/user 1":
+			w.WriteHeader(http.StatusNoContent)
+		case "/1/profiles/unknown":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Invalid API key"}`))
+		}
+	}))
+	defer server.Close()
+
+	d, err := NewPersonalizationDeleter("app", "key", "us")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.BaseURL = server.URL
+
+	if err := d.DeleteUserToken(context.Background(), "user 1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := d.DeleteUserToken(context.Background(), "unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteUserToken() error = %v, want ErrNotFound", err)
+	}
+	if err := d.DeleteUserToken(context.Background(), "other"); err == nil || !strings.Contains(err.Error(), "Invalid API key") {
+		t.Errorf("DeleteUserToken() error = %v, want the API error", err)
+	}
+}
+
+func TestAPIDeleter_Regions(t *testing.T) {
+	tests := []struct {
+		region             string
+		insightsURL        string
+		personalizationURL string
+	}{
+		{"us", "https://insights.us.algolia.io", "https://personalization.us.algolia.com"},
+		{"eu", "https://insights.de.algolia.io", "https://personalization.eu.algolia.com"},
+		{"de", "https://insights.de.algolia.io", "https://personalization.eu.algolia.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			insights, err := NewInsightsDeleter("app", "key", tt.region)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			perso, err := NewPersonalizationDeleter("app", "key", tt.region)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if insights.BaseURL != tt.insightsURL || perso.BaseURL != tt.personalizationURL {
+				t.Errorf("got %s and %s, want %s and %s", insights.BaseURL, perso.BaseURL, tt.insightsURL, tt.personalizationURL)
+			}
+		})
+	}
+
+	if _, err := NewInsightsDeleter("app", "key", "fr"); err == nil {
+		t.Errorf("expected an error for an unknown region")
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/algolia/fake-insights-generator/pkg/cleanup"
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+	"github.com/algolia/fake-insights-generator/pkg/utils"
+)
+
+type cleanupOptions struct {
+	IO *iostreams.IOStreams
+
+	TokensFile  string
+	Concurrency int
+	DryRun      bool
+	Yes         bool
+
+	Deleters []cleanup.Deleter
+}
+
+// NewCleanupCmd creates and returns a cleanup command
+func NewCleanupCmd() *cobra.Command {
+	opts := &cleanupOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete the data of the generated users from Insights and Personalization",
+		Long: `Delete the data of the user tokens recorded by the events command (see --record-tokens): their events
+in the Insights API and their profiles in the Personalization API. The tokens whose deletion failed are kept
+in the tokens file, to retry.`,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.InitializeConfig(cmd, "cleanup")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.IO = iostreams.System()
+
+			appId := cmd.Flag("app-id").Value.String()
+			apiKey := cmd.Flag("api-key").Value.String()
+			regionName := cmd.Flag("region").Value.String()
+
+			if !opts.DryRun {
+				if appId == "" || apiKey == "" {
+					return fmt.Errorf("missing required flags: app-id, api-key")
+				}
+				insightsDeleter, err := cleanup.NewInsightsDeleter(appId, apiKey, regionName)
+				if err != nil {
+					return err
+				}
+				persoDeleter, err := cleanup.NewPersonalizationDeleter(appId, apiKey, regionName)
+				if err != nil {
+					return err
+				}
+				opts.Deleters = []cleanup.Deleter{insightsDeleter, persoDeleter}
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			return runCleanupCmd(ctx, opts)
+		},
+	}
+
+	cmd.Flags().String("app-id", "", "Algolia application ID")
+	cmd.Flags().String("api-key", "", "Algolia API key")
+	cmd.Flags().String("region", defaultRegion, "region of the Insights and Personalization APIs: us, eu or de")
+
+	cmd.Flags().StringVar(&opts.TokensFile, "tokens", "user-tokens.txt", "file of the user tokens to delete, one per line")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", 10, "number of user tokens deleted concurrently")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "only list the user tokens, without deleting their data")
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "delete the data without confirmation")
+
+	return cmd
+}
+
+func runCleanupCmd(ctx context.Context, opts *cleanupOptions) error {
+	cs := opts.IO.ColorScheme()
+
+	tokens, err := events.LoadTokens(opts.TokensFile)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		fmt.Fprintf(opts.IO.Out, "%s No user tokens to clean up in %s\n", cs.SuccessIcon(), opts.TokensFile)
+		return nil
+	}
+
+	if opts.DryRun {
+		for _, token := range tokens {
+			fmt.Fprintln(opts.IO.Out, token)
+		}
+		fmt.Fprintf(opts.IO.ErrOut, "%s Dry run: the data of %d user tokens would be deleted\n", cs.WarningIcon(), len(tokens))
+		return nil
+	}
+	if !opts.Yes {
+		ok, err := confirm(opts.IO, fmt.Sprintf("Delete the events and profiles of %s user tokens?", cs.Bold(fmt.Sprint(len(tokens)))))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintf(opts.IO.Out, "%s Cleanup canceled\n", cs.WarningIcon())
+			return nil
+		}
+	}
+
+	if opts.IO.IsStdoutTTY() {
+		opts.IO.StartProgressIndicatorWithLabel(fmt.Sprintf("Deleting the data of %d user tokens...", len(tokens)))
+	}
+	report := cleanup.Run(ctx, tokens, opts.Concurrency, opts.Deleters...)
+	if opts.IO.IsStdoutTTY() {
+		opts.IO.StopProgressIndicator()
+	}
+
+	// The tokens file keeps the tokens to retry.
+	if err := events.SaveTokens(opts.TokensFile, report.Remaining); err != nil {
+		return err
+	}
+
+	for _, d := range opts.Deleters {
+		result := report.Results[d.Name()]
+		icon := cs.SuccessIcon()
+		if result.Failed > 0 {
+			icon = cs.FailureIcon()
+		}
+		fmt.Fprintf(opts.IO.Out, "%s %s: %d deleted, %d not found, %d failed\n",
+			icon, d.Name(), result.Deleted, result.NotFound, result.Failed)
+		if result.LastError != nil {
+			fmt.Fprintf(opts.IO.ErrOut, "  last error: %v\n", result.LastError)
+		}
+	}
+	if len(report.Remaining) > 0 {
+		return fmt.Errorf("%d user tokens remaining in %s: run the cleanup again to retry", len(report.Remaining), opts.TokensFile)
+	}
+	return nil
+}
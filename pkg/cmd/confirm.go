@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+)
+
+// confirm asks a yes/no question, the answer being no by default.
+// Without a terminal to prompt, the --yes flag is needed.
+func confirm(io *iostreams.IOStreams, question string) (bool, error) {
+	if !io.CanPrompt() {
+		return false, fmt.Errorf("use --yes to proceed without confirmation")
+	}
+	fmt.Fprintf(io.Out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(io.In).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
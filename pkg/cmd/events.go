@@ -1,26 +1,42 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math/rand"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/analytics"
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
 	"github.com/spf13/cobra"
 
 	"github.com/algolia/fake-insights-generator/pkg/events"
 	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+	"github.com/algolia/fake-insights-generator/pkg/scenario"
 	"github.com/algolia/fake-insights-generator/pkg/utils"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
+// eventsOptions are the options of the events generation shared by the events and serve commands.
+type eventsOptions struct {
+	Seed               int64
+	MaxQPS             float64
+	SearchRetries      int
+	DiscoverABTest     bool
+	QueryVariationRate float64
 }
 
 // NewEventsCmd creates and returns an events command
 func NewEventsCmd() *cobra.Command {
 	cfg := &events.Config{}
+	opts := &eventsOptions{}
+	var checkpointFileName string
+	var resume bool
 
 	cmd := &cobra.Command{
 		Use:   "events",
@@ -31,76 +47,140 @@ func NewEventsCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg.IO = iostreams.System()
 
-			// Search terms
-			searchTermsFileName := cmd.Flag("search-terms").Value.String()
-			searches, err := events.NewSearchTerms(searchTermsFileName)
-			if err != nil {
+			if err := loadEventsScenario(cmd, cfg); err != nil {
 				return err
 			}
-			cfg.SearchTerms = searches
 
-			// Users tags
-			usersTagsFileName := cmd.Flag("user-tags").Value.String()
-			if usersTagsFileName != "" {
-				tagsCollection, err := events.LoadTags(usersTagsFileName)
-				if err != nil {
+			// Accelerator origin: the former linear growth, +100% in 200 days.
+			if origin := cmd.Flag("accelerator-origin").Value.String(); origin != "" && !cmd.Flags().Changed("growth") {
+				if err := cmd.Flags().Set("growth", "linear:origin="+origin); err != nil {
 					return err
 				}
-				cfg.TagsCollection = tagsCollection
+			}
+			if err := setupGrowth(cmd, cfg); err != nil {
+				return err
+			}
+			// The growth of the number of users is computed once, at the start of the run.
+			grownUsers := cfg.NumberOfUsers
+			if cfg.Growth != nil {
+				grownUsers = cfg.Growth.Users(cfg.NumberOfUsers, time.Now())
 			}
 
-			// Personas
-			personasFileName := cmd.Flag("personas").Value.String()
-			if personasFileName != "" {
-				personas, err := events.NewUsersFromFile(cfg, personasFileName)
+			// Checkpoint: a resumed run must use the same seed and number of users to generate the same users.
+			seed := opts.Seed
+			var checkpoint *events.Checkpoint
+			if resume {
+				if checkpointFileName == "" {
+					return fmt.Errorf("the --resume flag requires the --checkpoint flag")
+				}
+				var err error
+				checkpoint, err = events.LoadCheckpoint(checkpointFileName)
 				if err != nil {
 					return err
 				}
-				cfg.PersonaUsers = personas
+				if seed != 0 && seed != checkpoint.Seed {
+					return fmt.Errorf("the --seed flag doesn't match the checkpoint seed (%d)", checkpoint.Seed)
+				}
+				if err := checkpoint.Check(cfg); err != nil {
+					return err
+				}
+				seed = checkpoint.Seed
+				grownUsers = checkpoint.Users()
+			} else if checkpointFileName != "" {
+				if seed == 0 {
+					seed = time.Now().UnixNano()
+				}
+				checkpoint = events.NewCheckpoint(checkpointFileName, seed, cfg, grownUsers)
 			}
-
-			// Events Names
-			eventsNamesFileName := cmd.Flag("events-names").Value.String()
-			if eventsNamesFileName != "" {
-				eventsNames, err := events.EventNamesFromFile(eventsNamesFileName)
+			cfg.Checkpoint = checkpoint
+			cfg.Rand = utils.NewRand(seed)
+			cfg.NumberOfUsers = grownUsers
+
+			// Backfill window
+			from := cmd.Flag("from").Value.String()
+			to := cmd.Flag("to").Value.String()
+			if to != "" && from == "" {
+				return fmt.Errorf("the --to flag requires the --from flag")
+			}
+			if from != "" {
+				fromDate, err := time.ParseInLocation("2006-01-02", from, time.Local)
+				if err != nil {
+					return err
+				}
+				toDate := time.Now()
+				if to != "" {
+					toDate, err = time.ParseInLocation("2006-01-02", to, time.Local)
+					if err != nil {
+						return err
+					}
+					// The end date is inclusive.
+					toDate = toDate.Add(24 * time.Hour)
+				}
+				if !fromDate.Before(toDate) || fromDate.After(time.Now()) {
+					return fmt.Errorf("invalid backfill window: %s - %s", from, to)
+				}
+				cfg.Window, err = events.NewWindow(cfg.Rand, fromDate, toDate)
 				if err != nil {
 					return err
 				}
-				cfg.EventsNames = eventsNames
 			}
 
-			// Algolia clients (search and insights)
-			appId := cmd.Flag("app-id").Value.String()
-			apiKey := cmd.Flag("api-key").Value.String()
-			indexName := cmd.Flag("index-name").Value.String()
+			if err := setupEvents(cmd, cfg, opts); err != nil {
+				return err
+			}
 
-			if appId == "" || apiKey == "" || indexName == "" {
-				return fmt.Errorf("missing required flags: app-id, api-key, index-name")
+			// Events sinks
+			sinkSpec := cmd.Flag("sink").Value.String()
+			insightsSink, err := openEventsOutputs(cmd, cfg)
+			if err != nil {
+				return err
 			}
 
-			searchClient := search.NewClient(appId, apiKey)
+			// The stats table is not printed when the events are written to stdout.
+			showStats := !hasSink(sinkSpec, "stdout")
 
-			cfg.SearchIndex = searchClient.InitIndex(indexName)
-			cfg.InsightsClient = insights.NewClient(appId, apiKey)
+			// On Ctrl-C, the run stops and the events generated so far are flushed.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			err = runEventsCmd(ctx, cfg, showStats)
+			if closeErr := closeEventsOutputs(cfg); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
 
-			// Accelerator origin
-			origin := cmd.Flag("accelerator-origin").Value.String()
-			var acceleratorOrigin time.Time
-			if origin != "" {
-				acceleratorOrigin, err = time.Parse("2006-01-02", origin)
-				if err != nil {
-					return err
-				}
-				cfg.AcceleratorOrigin = &acceleratorOrigin
+			if insightsSink != nil && insightsSink.Dropped > 0 {
+				fmt.Fprintf(cfg.IO.ErrOut, "Warning: %d events older than 4 days were not sent to Insights\n", insightsSink.Dropped)
 			}
-			return runEventsCmd(cfg)
+			return nil
 		},
 	}
 
+	addEventsFlags(cmd, cfg, opts)
+
+	cmd.Flags().String("accelerator-origin", "", "")
+	_ = cmd.Flags().MarkDeprecated("accelerator-origin", "use --growth linear:origin=<date> instead")
+
+	cmd.Flags().String("from", "", "backfill: start date of the events window (YYYY-MM-DD)")
+	cmd.Flags().String("to", "", "backfill: end date of the events window (YYYY-MM-DD), defaults to today")
+	cmd.Flags().StringVar(&cfg.BackfillFile, "backfill-csv", "", "backfill: CSV file for the events too old to be sent to Insights")
+
+	cmd.Flags().StringVar(&checkpointFileName, "checkpoint", "", "file recording the progress of the run, to resume it if interrupted")
+	cmd.Flags().BoolVar(&resume, "resume", false, "resume an interrupted run from the checkpoint file")
+
+	return cmd
+}
+
+// addEventsFlags defines the flags of the events generation: the index, the files, the rates,
+// the A/B test and the destinations of the events.
+func addEventsFlags(cmd *cobra.Command, cfg *events.Config, opts *eventsOptions) {
 	cmd.Flags().String("app-id", "", "Algolia application ID")
 	cmd.Flags().String("api-key", "", "Algolia API key")
 	cmd.Flags().String("index-name", "", "Algolia index name")
+	cmd.Flags().String("records", "", "offline mode: JSON records dump to search into instead of the Algolia index")
 
+	cmd.Flags().String("scenario", "", "scenario file (YAML or JSON): its sections replace the files below, its rates replace the flags defaults")
 	cmd.Flags().String("search-terms", "searches.json", "searches terms file")
 	cmd.Flags().String("user-tags", "user-tags.json", "users tags file")
 	cmd.Flags().String("personas", "personas.json", "users persona file")
@@ -109,24 +189,314 @@ func NewEventsCmd() *cobra.Command {
 	cmd.Flags().IntVar(&cfg.NumberOfUsers, "users", 100, "number of users")
 	cmd.Flags().IntVar(&cfg.SearchesPerUser, "searches-per-user", 4, "number of searches per user")
 	cmd.Flags().DurationVar(&cfg.SearchDelay, "delay-between-searches", 46, "delay between searches for each user, in seconds")
+	cmd.Flags().IntVar(&cfg.Concurrency, "concurrency", 100, "number of users generating events concurrently")
+	cmd.Flags().Float64Var(&opts.MaxQPS, "max-qps", 0, "maximum number of search queries per second (unlimited if 0)")
+	cmd.Flags().IntVar(&opts.SearchRetries, "search-retries", 3, "number of retries of a search on transient errors")
 
 	cmd.Flags().IntVar(&cfg.HitsPerPage, "hits-per-page", 20, "number of hits per page")
 	cmd.Flags().IntVar(&cfg.ClickPosition, "average-click-position", 1, "average click position")
 	cmd.Flags().Float64Var(&cfg.ClickThroughRate, "click-through-rate", 20, "click through rate")
 	cmd.Flags().Float64Var(&cfg.ConversionRate, "conversion-rate", 10, "conversion rate")
-
-	cmd.Flags().String("accelerator-origin", "", "")
+	cmd.Flags().Float64Var(&cfg.RefinementRate, "refinement-rate", 10, "percentage of sessions with a refinement search (other filters values)")
+	cmd.Flags().Float64Var(&cfg.ViewRate, "view-rate", 20, "percentage of sessions sending view events for the hits seen (if the events names file has view events names)")
+	cmd.Flags().Float64Var(&cfg.PaginationRate, "pagination-rate", 5, "percentage of sessions going to the next page of results")
+	cmd.Flags().Float64Var(&cfg.TypingRate, "typing-rate", 0, "percentage of sessions typing the query in a search-as-you-type UI (one search per keystroke)")
+	cmd.Flags().Float64Var(&cfg.TypoRate, "typo-rate", 10, "percentage of typed queries with a typo, corrected by the user")
+	cmd.Flags().DurationVar(&cfg.KeystrokeDelay, "keystroke-delay", 200*time.Millisecond, "average delay between two keystrokes when typing a query")
+	cmd.Flags().Float64Var(&opts.QueryVariationRate, "query-variation-rate", 0, "percentage of searches with a variation of the query (typo, plural, casing, word order)")
+	cmd.Flags().String("query-variations", "typo:4,plural:2,casing:2,word_order:1", "weights of the query variations: typo, plural, casing, word_order")
+	cmd.Flags().String("growth", "", "growth of the number of users and of the rates over time: <model>:<param>=<value>,... with the linear, logistic, step or seasonal model")
 
 	cmd.Flags().IntVar(&cfg.ABTest.VariantID, "ab-test-variant-id", 0, "A/B Test: ID of the variant to favorize")
 	cmd.Flags().Float64Var(&cfg.ABTest.ClickThroughRate, "ab-test-variant-ctr", 4, "A/B Test: How much CTR +% for the selected variant")
 	cmd.Flags().Float64Var(&cfg.ABTest.ConversionRate, "ab-test-variant-cvr", 2, "A/B Test: How much CTR +% for the selected variant")
+	cmd.Flags().String("ab-test-variants", "", "A/B Test: JSON file of the CTR, CVR and click position targets of each variant")
+	cmd.Flags().BoolVar(&opts.DiscoverABTest, "ab-test-discover", false, "A/B Test: find the running A/B test of the index, to identify the variants by their index name")
+
+	cmd.Flags().Int64Var(&opts.Seed, "seed", 0, "seed of the random choices, to reproduce a run (random if 0)")
+
+	cmd.Flags().String("record-tokens", "user-tokens.txt", "file recording the user tokens of the events sent to Insights, to delete their data with the cleanup command")
+	cmd.Flags().Bool("no-record-tokens", false, "do not record the user tokens of the events sent to Insights")
 
 	cmd.Flags().BoolVar(&cfg.DryRun, "dry-run", false, "if false, events will not be sent and analytics will be disabled on search queries")
+	cmd.Flags().String("sink", "insights", "comma separated list of events destinations: insights, ndjson:<file>, csv:<file>, stdout")
+}
 
-	return cmd
+// loadEventsScenario loads the scenario file, if any: its rates are the defaults of the flags,
+// its sections replace the loose files.
+func loadEventsScenario(cmd *cobra.Command, cfg *events.Config) error {
+	scenarioFileName := cmd.Flag("scenario").Value.String()
+	if scenarioFileName == "" {
+		return nil
+	}
+	scn, err := scenario.Load(scenarioFileName)
+	if err != nil {
+		return err
+	}
+	if err := setScenarioFlags(cmd, scn.Flags()); err != nil {
+		return err
+	}
+	return scn.ApplyEvents(cfg)
+}
+
+// setupGrowth sets the growth of the number of users and of the rates of the growth flag,
+// instead of the scenario one.
+func setupGrowth(cmd *cobra.Command, cfg *events.Config) error {
+	if growthSpec := cmd.Flag("growth").Value.String(); growthSpec != "" {
+		growth, err := events.ParseGrowth(growthSpec)
+		if err != nil {
+			return err
+		}
+		cfg.Growth = growth
+	}
+	return nil
+}
+
+// setupEvents loads the files and creates the Algolia clients of the events generation.
+func setupEvents(cmd *cobra.Command, cfg *events.Config, opts *eventsOptions) error {
+	// Search terms
+	var err error
+	if cfg.SearchTerms == nil {
+		searchTermsFileName := cmd.Flag("search-terms").Value.String()
+		cfg.SearchTerms, err = events.NewSearchTerms(searchTermsFileName)
+		if err != nil {
+			return err
+		}
+	}
+	if capped := cfg.CappedConversionTerms(); len(capped) > 0 {
+		var terms []string
+		for i, term := range capped {
+			if i == 5 {
+				terms = append(terms, "...")
+				break
+			}
+			terms = append(terms, fmt.Sprintf("%q", term.Term))
+		}
+		fmt.Fprintf(cfg.IO.ErrOut, "%s %d search terms have a conversion rate above their click-through rate, they convert at their click-through rate at most: %s\n",
+			cfg.IO.ColorScheme().WarningIcon(), len(capped), strings.Join(terms, ", "))
+	}
+
+	// Users tags
+	usersTagsFileName := cmd.Flag("user-tags").Value.String()
+	if usersTagsFileName != "" && cfg.TagsCollection == nil {
+		tagsCollection, err := events.LoadTags(usersTagsFileName)
+		if err != nil {
+			return err
+		}
+		cfg.TagsCollection = tagsCollection
+	}
+
+	// Personas
+	personasFileName := cmd.Flag("personas").Value.String()
+	if personasFileName != "" && cfg.PersonaUsers == nil {
+		personas, err := events.NewUsersFromFile(cfg, personasFileName)
+		if err != nil {
+			return err
+		}
+		cfg.PersonaUsers = personas
+	}
+
+	// Events Names
+	eventsNamesFileName := cmd.Flag("events-names").Value.String()
+	if eventsNamesFileName != "" && cfg.EventsNames == nil {
+		eventsNames, err := events.EventNamesFromFile(eventsNamesFileName)
+		if err != nil {
+			return err
+		}
+		cfg.EventsNames = eventsNames
+	}
+
+	// Algolia clients (search and insights)
+	appId := cmd.Flag("app-id").Value.String()
+	apiKey := cmd.Flag("api-key").Value.String()
+	indexName := cmd.Flag("index-name").Value.String()
+
+	// Offline mode: the searches are done on a local records dump.
+	recordsFileName := cmd.Flag("records").Value.String()
+	if recordsFileName != "" {
+		if indexName == "" {
+			return fmt.Errorf("missing required flag: index-name")
+		}
+		if !cfg.DryRun && (appId == "" || apiKey == "") {
+			return fmt.Errorf("missing required flags to send events: app-id, api-key (or use --dry-run)")
+		}
+		localIndex, err := events.NewLocalIndex(indexName, recordsFileName)
+		if err != nil {
+			return err
+		}
+		cfg.SearchIndex = localIndex
+		if appId != "" && apiKey != "" {
+			cfg.InsightsClient = insights.NewClient(appId, apiKey)
+		}
+	} else {
+		if appId == "" || apiKey == "" || indexName == "" {
+			return fmt.Errorf("missing required flags: app-id, api-key, index-name")
+		}
+
+		searchClient := search.NewClient(appId, apiKey)
+
+		cfg.SearchIndex = searchClient.InitIndex(indexName)
+		cfg.InsightsClient = insights.NewClient(appId, apiKey)
+	}
+
+	// Query variations
+	cfg.QueryVariations, err = events.ParseQueryVariationsWeights(cmd.Flag("query-variations").Value.String())
+	if err != nil {
+		return err
+	}
+	cfg.QueryVariations.Rate = opts.QueryVariationRate
+
+	// A/B test variants targets, eventually identified by their index in the running A/B test.
+	abTestVariantsFileName := cmd.Flag("ab-test-variants").Value.String()
+	if abTestVariantsFileName != "" && cfg.ABTest.Variants == nil {
+		cfg.ABTest.Variants, err = events.LoadABTestVariants(abTestVariantsFileName)
+		if err != nil {
+			return err
+		}
+	}
+	var abTest *analytics.ABTestResponse
+	if opts.DiscoverABTest {
+		if appId == "" || apiKey == "" {
+			return fmt.Errorf("missing required flags to discover the A/B test: app-id, api-key")
+		}
+		abTest, err = events.DiscoverABTest(analytics.NewClient(appId, apiKey), indexName)
+		if err != nil {
+			return err
+		}
+		if cfg.IO.IsStdoutTTY() {
+			fmt.Fprintf(cfg.IO.Out, "%s A/B Test %q is running on %s\n", cfg.IO.ColorScheme().SuccessIcon(), abTest.Name, indexName)
+		}
+	}
+	if err := cfg.ABTest.Variants.Resolve(abTest); err != nil {
+		return err
+	}
+
+	// Rate limiting and retries of the searches
+	retrySearcher := &events.RetrySearcher{
+		Searcher: cfg.SearchIndex,
+		Retries:  opts.SearchRetries,
+		Backoff:  500 * time.Millisecond,
+	}
+	if opts.MaxQPS > 0 {
+		retrySearcher.Limiter = events.NewRateLimiter(opts.MaxQPS, int(math.Ceil(opts.MaxQPS)))
+	}
+	cfg.SearchIndex = retrySearcher
+	return nil
+}
+
+// openEventsOutputs creates the events sinks and the user tokens recorder.
+// They are closed with closeEventsOutputs.
+func openEventsOutputs(cmd *cobra.Command, cfg *events.Config) (*events.InsightsSink, error) {
+	insightsSink, err := newEventsSink(cfg, cmd.Flag("sink").Value.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// User tokens, recorded for the cleanup command when the events are sent to Insights
+	// (there is no insights sink in dry run mode).
+	tokensFileName := cmd.Flag("record-tokens").Value.String()
+	noRecord, _ := cmd.Flags().GetBool("no-record-tokens")
+	if insightsSink != nil && tokensFileName != "" && !noRecord {
+		cfg.Tokens, err = events.NewTokenRecorder(tokensFileName)
+		if err != nil {
+			cfg.Sink.Close()
+			return nil, err
+		}
+	}
+	return insightsSink, nil
+}
+
+// closeEventsOutputs closes the events sinks and the user tokens recorder.
+func closeEventsOutputs(cfg *events.Config) error {
+	err := cfg.Sink.Close()
+	if cfg.Tokens != nil {
+		if closeErr := cfg.Tokens.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
-func runEventsCmd(cfg *events.Config) error {
+// setScenarioFlags sets the flags defined by the scenario, unless they are set on the command line
+// or in the configuration file.
+func setScenarioFlags(cmd *cobra.Command, flags map[string]string) error {
+	for name, value := range flags {
+		if cmd.Flags().Changed(name) {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("scenario: %v", err)
+		}
+	}
+	return nil
+}
+
+// newEventsSink creates the events sinks from the sink flag value.
+// In dry run mode, the insights sink is ignored.
+func newEventsSink(cfg *events.Config, spec string) (*events.InsightsSink, error) {
+	if cfg.BackfillFile != "" {
+		if !hasSink(spec, "insights") {
+			return nil, fmt.Errorf("the --backfill-csv flag requires the insights sink")
+		}
+		if cfg.DryRun {
+			fmt.Fprintf(cfg.IO.ErrOut, "%s Dry run is ON: --backfill-csv is ignored\n", cfg.IO.ColorScheme().WarningIcon())
+		}
+	}
+	if cfg.DryRun {
+		var sinks []string
+		for _, s := range strings.Split(spec, ",") {
+			if !hasSink(s, "insights") {
+				sinks = append(sinks, s)
+			}
+		}
+		spec = strings.Join(sinks, ",")
+	}
+
+	sinks, insightsSink, err := events.NewSinks(spec, cfg.InsightsClient, cfg.IO.Out)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Sink = sinks
+
+	if insightsSink != nil && cfg.BackfillFile != "" {
+		insightsSink.Backfill, err = events.NewCSVFileSink(cfg.BackfillFile)
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+	}
+	return insightsSink, nil
+}
+
+// hasSink returns true if the sink flag value has a sink with this name.
+func hasSink(spec string, name string) bool {
+	for _, sinkName := range events.SinkNames(spec) {
+		if sinkName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// abTestVariantTargets describes the targets of an A/B test variant.
+func abTestVariantTargets(variant events.ABTestVariant) string {
+	var targets []string
+	if variant.ClickThroughRate != 0 {
+		targets = append(targets, fmt.Sprintf("%.2f%% CTR", variant.ClickThroughRate))
+	}
+	if variant.ConversionRate != 0 {
+		targets = append(targets, fmt.Sprintf("%.2f%% CVR", variant.ConversionRate))
+	}
+	if variant.ClickPosition != 0 {
+		targets = append(targets, fmt.Sprintf("click position %d", variant.ClickPosition))
+	}
+	if len(targets) == 0 {
+		return "the default rates"
+	}
+	return strings.Join(targets, " / ")
+}
+
+func runEventsCmd(ctx context.Context, cfg *events.Config, showStats bool) error {
 	cs := cfg.IO.ColorScheme()
 	if cfg.IO.IsStdoutTTY() {
 		if cfg.DryRun {
@@ -137,30 +507,58 @@ func runEventsCmd(cfg *events.Config) error {
 
 		if cfg.ABTest.VariantID > 0 {
 			fmt.Fprintf(cfg.IO.Out, "%s A/B Test is ON: %s variant will be favorized (+%.2f%% CTR / +%.2f%% CVR)\n",
-				cs.WarningIcon(), cs.Bold(string(cfg.ABTest.VariantID)), cfg.ABTest.ClickThroughRate, cfg.ABTest.ConversionRate)
+				cs.WarningIcon(), cs.Bold(strconv.Itoa(cfg.ABTest.VariantID)), cfg.ABTest.ClickThroughRate, cfg.ABTest.ConversionRate)
+		}
+		for _, variant := range cfg.ABTest.Variants {
+			fmt.Fprintf(cfg.IO.Out, "%s A/B Test is ON: %s variant will target %s\n",
+				cs.WarningIcon(), cs.Bold(strconv.Itoa(variant.ID)), abTestVariantTargets(variant))
+		}
+
+		if cfg.Window != nil {
+			fmt.Fprintf(cfg.IO.Out, "%s Backfill is ON: Events will be spread between %s and %s\n",
+				cs.WarningIcon(), cfg.Window.From.Format("2006-01-02"), cfg.Window.To.Format("2006-01-02"))
+			if cfg.Window.IsOutOfRange() {
+				if cfg.BackfillFile != "" {
+					fmt.Fprintf(cfg.IO.Out, "%s Events older than 4 days will be written to %s instead of being sent to Insights\n",
+						cs.WarningIcon(), cs.Bold(cfg.BackfillFile))
+				} else {
+					fmt.Fprintf(cfg.IO.Out, "%s Events older than 4 days will be DROPPED: use --backfill-csv to keep them\n", cs.WarningIcon())
+				}
+			}
 		}
 
 		cfg.IO.StartProgressIndicatorWithLabel("Generating events...")
 	}
 
-	stats, err := events.Run(cfg)
+	stats, err := events.Run(ctx, cfg)
 
 	if cfg.IO.IsStdoutTTY() {
 		cfg.IO.StopProgressIndicator()
 	}
 
-	if err != nil {
+	interrupted := errors.Is(err, context.Canceled)
+	if err != nil && !interrupted {
 		return err
 	}
 
-	if cfg.IO.IsStdoutTTY() {
+	if interrupted {
+		fmt.Fprintf(cfg.IO.ErrOut, "%s Interrupted: the events generated so far have been flushed\n", cs.WarningIcon())
+		if cfg.Checkpoint != nil {
+			fmt.Fprintf(cfg.IO.ErrOut, "%s Run the same command with --resume to generate the remaining events\n\n", cs.WarningIcon())
+		}
+	} else if cfg.IO.IsStdoutTTY() {
 		fmt.Fprintf(cfg.IO.Out, "%s All Done!\n\n", cs.SuccessIcon())
 	}
 
+	if !showStats {
+		return nil
+	}
+
 	table := utils.NewTablePrinter(cfg.IO)
 	if table.IsTTY() {
 		table.AddField(cs.Bold("TERM"), nil, nil)
 		table.AddField(cs.Bold("SEARCHES"), nil, nil)
+		table.AddField(cs.Bold("VIEWS"), nil, nil)
 		table.AddField(cs.Bold("CLICKS"), nil, nil)
 		table.AddField(cs.Bold("CLICK THROUGH RATE"), nil, nil)
 		table.AddField(cs.Bold("AVG CLICK POSITION"), nil, nil)
@@ -172,8 +570,9 @@ func runEventsCmd(cfg *events.Config) error {
 
 	for _, stats := range stats {
 		table.AddField(stats.Stats.Term, nil, nil)
-		table.AddField(fmt.Sprintf("%d", stats.Stats.TotalSearches()), nil, nil)
-		table.AddField(fmt.Sprintf("%d", stats.Stats.TotalEventsOfType(insights.EventTypeClick)), nil, nil)
+		table.AddField(fmt.Sprintf("%d", stats.TotalSearches), nil, nil)
+		table.AddField(fmt.Sprintf("%d", stats.Stats.TotalViews()), nil, nil)
+		table.AddField(fmt.Sprintf("%d", stats.Stats.TotalClicks()), nil, nil)
 		table.AddField(fmt.Sprintf("%.2f%%", stats.Stats.ClickThroughRatePercent()), nil, nil)
 		table.AddField(fmt.Sprintf("%.2f", stats.Stats.MeanClickPosition()), nil, nil)
 		table.AddField(fmt.Sprintf("%.2f", stats.Stats.MedianClickPosition()), nil, nil)
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+	"github.com/spf13/cobra"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+	"github.com/algolia/fake-insights-generator/pkg/scaffold"
+	"github.com/algolia/fake-insights-generator/pkg/utils"
+)
+
+// NewInitCmd creates and returns an init command
+func NewInitCmd() *cobra.Command {
+	opts := scaffold.Options{}
+	var output string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create a starter scenario file from an existing index",
+		Long: `Create a starter scenario file from the records of an existing index: search terms drawn from the
+records names and categories, weighted filters on the facet values, and personas built from facet clusters.
+The facets are read from the attributesForFaceting setting of the index (or given with --facets).
+The scenario is meant to be edited, then used with the --scenario flag of the events command.`,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Same configuration as the events command.
+			return utils.InitializeConfig(cmd, "events")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			io := iostreams.System()
+
+			appId := cmd.Flag("app-id").Value.String()
+			apiKey := cmd.Flag("api-key").Value.String()
+			indexName := cmd.Flag("index-name").Value.String()
+			recordsFileName := cmd.Flag("records").Value.String()
+
+			if !force {
+				if _, err := os.Stat(output); err == nil {
+					return fmt.Errorf("%s already exists, use --force to overwrite it", output)
+				}
+			}
+
+			if io.IsStdoutTTY() {
+				io.StartProgressIndicatorWithLabel("Browsing the records...")
+			}
+			var records []map[string]interface{}
+			var err error
+			if recordsFileName != "" {
+				if len(opts.Facets) == 0 {
+					err = fmt.Errorf("the --records flag requires the --facets flag")
+				} else {
+					var localIndex *events.LocalIndex
+					localIndex, err = events.NewLocalIndex(indexName, recordsFileName)
+					if err == nil {
+						records = localIndex.Records
+					}
+				}
+			} else if appId == "" || apiKey == "" || indexName == "" {
+				err = fmt.Errorf("missing required flags: app-id, api-key, index-name")
+			} else {
+				index := search.NewClient(appId, apiKey).InitIndex(indexName)
+				if len(opts.Facets) == 0 {
+					opts.Facets, err = scaffold.Facets(index)
+				}
+				if err == nil {
+					records, err = scaffold.Records(index, opts.Attributes())
+				}
+			}
+			if io.IsStdoutTTY() {
+				io.StopProgressIndicator()
+			}
+			if err != nil {
+				return err
+			}
+
+			s, err := scaffold.Scaffold(records, opts)
+			if err != nil {
+				return err
+			}
+			if err := s.Save(output); err != nil {
+				return err
+			}
+
+			cs := io.ColorScheme()
+			fmt.Fprintf(io.Out, "%s Scenario written to %s: %d search terms and %d personas from %d records\n",
+				cs.SuccessIcon(), cs.Bold(output), len(s.SearchTerms), len(s.Personas), len(records))
+			if len(opts.Facets) == 0 {
+				fmt.Fprintf(io.Out, "%s No facets found: the search terms have no filters, use --facets to set them\n", cs.WarningIcon())
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("app-id", "", "Algolia application ID")
+	cmd.Flags().String("api-key", "", "Algolia API key")
+	cmd.Flags().String("index-name", "", "Algolia index name")
+	cmd.Flags().String("records", "", "offline mode: JSON records dump to read instead of the Algolia index")
+
+	cmd.Flags().StringSliceVar(&opts.Facets, "facets", nil, "facet attributes of the filters and personas (defaults to the attributesForFaceting of the index)")
+	cmd.Flags().StringVar(&opts.NameAttribute, "name-attribute", "name", "attribute of the records names")
+	cmd.Flags().IntVar(&opts.SearchTerms, "max-search-terms", 20, "maximum number of search terms")
+	cmd.Flags().IntVar(&opts.Personas, "max-personas", 3, "maximum number of personas")
+	cmd.Flags().IntVar(&opts.FacetValues, "facet-values", 5, "maximum number of values of a filter")
+
+	cmd.Flags().StringVarP(&output, "output", "o", "scenario.yml", "scenario file to write (YAML, or JSON with a .json extension)")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite the scenario file if it exists")
+
+	return cmd
+}
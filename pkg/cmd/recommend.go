@@ -2,7 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
@@ -11,16 +11,16 @@ import (
 
 	"github.com/algolia/fake-insights-generator/pkg/iostreams"
 	"github.com/algolia/fake-insights-generator/pkg/recommend"
+	"github.com/algolia/fake-insights-generator/pkg/scenario"
 	"github.com/algolia/fake-insights-generator/pkg/utils"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
-
 // NewRecommendCmd creates and returns a recommend command
 func NewRecommendCmd() *cobra.Command {
 	cfg := &recommend.Config{}
+	var seed int64
+	var windowDays int
+	trending := &recommend.Trending{}
 
 	cmd := &cobra.Command{
 		Use:   "recommend",
@@ -30,6 +30,45 @@ func NewRecommendCmd() *cobra.Command {
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg.IO = iostreams.System()
+			cfg.Rand = utils.NewRand(seed)
+
+			if cfg.ClicksPerObject < 1 || cfg.ConversionsPerObject < 1 || windowDays < 1 {
+				return fmt.Errorf("the --clicks-per-object, --conversions-per-object and --window-days flags must be at least 1")
+			}
+			cfg.Window = time.Duration(windowDays) * 24 * time.Hour
+			var err error
+			cfg.Multipliers, err = recommend.ParseMultipliers(cmd.Flag("category-multipliers").Value.String())
+			if err != nil {
+				return err
+			}
+			cfg.RelatedAttributes, err = recommend.ParseAttributes(cmd.Flag("related-attributes").Value.String())
+			if err != nil {
+				return err
+			}
+
+			// The trending flags replace the trending section of the recommend configuration.
+			trending.Facets, err = recommend.ParseTrendingFacets(cmd.Flag("trending-facets").Value.String())
+			if err != nil {
+				return err
+			}
+			if !trending.IsEmpty() {
+				if trending.Days < 1 || trending.Boost < 1 {
+					return fmt.Errorf("the --trending-days and --trending-boost flags must be at least 1")
+				}
+				cfg.Trending = trending
+			}
+
+			// Scenario: its recommend section replaces the recommend file.
+			if scenarioFileName := cmd.Flag("scenario").Value.String(); scenarioFileName != "" {
+				scn, err := scenario.Load(scenarioFileName)
+				if err != nil {
+					return err
+				}
+				if scn.Recommend == nil {
+					return fmt.Errorf("%s: missing recommend section", scenarioFileName)
+				}
+				cfg.Recommend = scn.Recommend
+			}
 
 			// Algolia client
 			appId := cmd.Flag("app-id").Value.String()
@@ -53,9 +92,58 @@ func NewRecommendCmd() *cobra.Command {
 	cmd.Flags().String("api-key", "", "Algolia API key")
 	cmd.Flags().String("index-name", "", "Algolia index name")
 
+	cmd.Flags().String("scenario", "", "scenario file (YAML or JSON) with a recommend section, instead of the recommend file")
+	cmd.Flags().StringVar(&cfg.RecommendFile, "recommend", recommend.DefaultRecommendFile, "recommend configuration file: facet and frequently bought together categories")
+	cmd.Flags().StringVar(&cfg.FacetName, "facet", "", "facet of the categories, instead of the facet_name of the recommend configuration")
+
+	cmd.Flags().IntVar(&cfg.ClicksPerObject, "clicks-per-object", recommend.DefaultClicksPerObject, "number of click events per object (similar items)")
+	cmd.Flags().IntVar(&cfg.ConversionsPerObject, "conversions-per-object", recommend.DefaultConversionsPerObject, "number of conversion events per object (frequently bought together)")
+	cmd.Flags().String("category-multipliers", "", "multipliers of the number of events of some categories and their subcategories, e.g. \"Women > Shoes:2,Men:0.5\"")
+	cmd.Flags().IntVar(&windowDays, "window-days", int(recommend.DefaultWindow.Hours()/24), "number of days the events are spread over, before now")
+
+	cmd.Flags().StringVar(&cfg.SimilarFile, "similar-output", recommend.DefaultSimilarFile, "CSV file of the similar items events")
+	cmd.Flags().StringVar(&cfg.FBTFile, "fbt-output", recommend.DefaultFBTFile, "CSV file of the frequently bought together events")
+	cmd.Flags().String("related-attributes", "", "weights of the attributes defining similar items for the related products events, e.g. \"brand:3,color:2,price:1\"")
+	cmd.Flags().StringVar(&cfg.RelatedFile, "related-output", recommend.DefaultRelatedFile, "CSV file of the related products events")
+	cmd.Flags().StringSliceVar(&trending.ObjectIDs, "trending-objects", nil, "objectIDs of the trending items")
+	cmd.Flags().String("trending-facets", "", "trending facet values, e.g. \"brand:Nike,color:red\"")
+	cmd.Flags().IntVar(&trending.Days, "trending-days", 7, "number of days of rising conversions of the trending items")
+	cmd.Flags().Float64Var(&trending.Boost, "trending-boost", 5, "multiplier of the conversions of the trending items on the last day, compared with the baseline")
+	cmd.Flags().StringVar(&cfg.TrendingFile, "trending-output", recommend.DefaultTrendingFile, "CSV file of the trending events")
+	cmd.Flags().BoolVar(&cfg.Send, "send", false, "send the events of the last 4 days to Insights, and write only the older ones to the CSV files")
+
+	cmd.Flags().Int64Var(&seed, "seed", 0, "seed of the random choices, to reproduce a run (random if 0)")
+
 	return cmd
 }
 
 func runRecommendCmd(cfg *recommend.Config) error {
-	return recommend.Run(cfg)
+	cs := cfg.IO.ColorScheme()
+	if cfg.IO.IsStdoutTTY() {
+		cfg.IO.StartProgressIndicatorWithLabel("Generating recommend events...")
+	}
+	outputs, err := recommend.Run(cfg)
+	if cfg.IO.IsStdoutTTY() {
+		cfg.IO.StopProgressIndicator()
+	}
+	if err != nil {
+		return err
+	}
+
+	written := 0
+	for _, output := range outputs {
+		written += output.Written
+		var destinations []string
+		if cfg.Send {
+			destinations = append(destinations, fmt.Sprintf("%d events sent to Insights", output.Sent))
+		}
+		if !cfg.Send || output.Written > 0 {
+			destinations = append(destinations, fmt.Sprintf("%d events written to %s", output.Written, output.File))
+		}
+		fmt.Fprintf(cfg.IO.Out, "%s %s: %s\n", cs.SuccessIcon(), output.Model, strings.Join(destinations, ", "))
+	}
+	if cfg.Send && written > 0 {
+		fmt.Fprintf(cfg.IO.Out, "%s Events older than 4 days can't be sent: upload the CSV files in the Recommend dashboard\n", cs.WarningIcon())
+	}
+	return nil
 }
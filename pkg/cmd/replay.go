@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+	"github.com/spf13/cobra"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+	"github.com/algolia/fake-insights-generator/pkg/utils"
+)
+
+type replayOptions struct {
+	IO     *iostreams.IOStreams
+	DryRun bool
+
+	FileName        string
+	IndexName       string
+	NewUserTokens   bool
+	UserTokenPrefix string
+	Seed            int64
+
+	InsightsClient *insights.Client
+}
+
+// NewReplayCmd creates and returns a replay command
+func NewReplayCmd() *cobra.Command {
+	opts := &replayOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "replay <file>",
+		Short: "Replay a previously exported events file (NDJSON or CSV)",
+		Args:  cobra.ExactArgs(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.InitializeConfig(cmd, "replay")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.IO = iostreams.System()
+			opts.FileName = args[0]
+
+			appId := cmd.Flag("app-id").Value.String()
+			apiKey := cmd.Flag("api-key").Value.String()
+			if !opts.DryRun {
+				if appId == "" || apiKey == "" {
+					return fmt.Errorf("missing required flags: app-id, api-key")
+				}
+				opts.InsightsClient = insights.NewClient(appId, apiKey)
+			}
+
+			return runReplayCmd(opts)
+		},
+	}
+
+	cmd.Flags().String("app-id", "", "Algolia application ID")
+	cmd.Flags().String("api-key", "", "Algolia API key")
+	cmd.Flags().StringVar(&opts.IndexName, "index-name", "", "Algolia index name to send the events to (mandatory for CSV files)")
+
+	cmd.Flags().BoolVar(&opts.NewUserTokens, "new-user-tokens", false, "replace each user token by a new random one")
+	cmd.Flags().StringVar(&opts.UserTokenPrefix, "user-token-prefix", "", "prefix of the new user tokens (implies --new-user-tokens)")
+
+	cmd.Flags().Int64Var(&opts.Seed, "seed", 0, "seed of the new user tokens, to reproduce a replay (random if 0)")
+
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "if true, events will not be sent")
+
+	return cmd
+}
+
+func runReplayCmd(opts *replayOptions) error {
+	cs := opts.IO.ColorScheme()
+
+	replayedEvents, err := events.ReadEventsFile(opts.FileName)
+	if err != nil {
+		return err
+	}
+
+	if opts.IndexName != "" {
+		events.RemapIndex(replayedEvents, opts.IndexName)
+	}
+	for _, event := range replayedEvents {
+		if event.Index == "" {
+			return fmt.Errorf("missing index name in %s: use the --index-name flag", opts.FileName)
+		}
+	}
+	if opts.NewUserTokens || opts.UserTokenPrefix != "" {
+		events.RemapUserTokens(utils.NewRand(opts.Seed), replayedEvents, opts.UserTokenPrefix)
+	}
+	events.Retime(replayedEvents, time.Now())
+
+	if opts.DryRun {
+		fmt.Fprintf(opts.IO.Out, "%s Dry run is ON: %d events WILL NOT be sent to Insights\n", cs.WarningIcon(), len(replayedEvents))
+		return nil
+	}
+
+	if opts.IO.IsStdoutTTY() {
+		opts.IO.StartProgressIndicatorWithLabel("Replaying events...")
+	}
+
+	sink := &events.InsightsSink{Client: opts.InsightsClient}
+	err = sink.Write(replayedEvents)
+
+	if opts.IO.IsStdoutTTY() {
+		opts.IO.StopProgressIndicator()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(opts.IO.Out, "%s %d events replayed\n", cs.SuccessIcon(), len(replayedEvents)-sink.Dropped)
+	if sink.Dropped > 0 {
+		fmt.Fprintf(opts.IO.ErrOut, "Warning: %d events older than 4 days were not sent to Insights\n", sink.Dropped)
+	}
+	return nil
+}
@@ -13,8 +13,15 @@ func NewRootCmd() *cobra.Command {
 		},
 	}
 
+	rootCmd.AddCommand(NewInitCmd())
 	rootCmd.AddCommand(NewEventsCmd())
+	rootCmd.AddCommand(NewServeCmd())
 	rootCmd.AddCommand(NewRecommendCmd())
+	rootCmd.AddCommand(NewReplayCmd())
+	rootCmd.AddCommand(NewValidateCmd())
+	rootCmd.AddCommand(NewScenarioCmd())
+	rootCmd.AddCommand(NewSnapshotCmd())
+	rootCmd.AddCommand(NewCleanupCmd())
 
 	return rootCmd
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+	"github.com/algolia/fake-insights-generator/pkg/scenario"
+)
+
+// NewScenarioCmd creates and returns a scenario command
+func NewScenarioCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scenario",
+		Short: "Manage the scenario files",
+	}
+
+	cmd.AddCommand(newScenarioImportCmd())
+
+	return cmd
+}
+
+func newScenarioImportCmd() *cobra.Command {
+	var files scenario.Files
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Convert the loose configuration files to a single scenario file",
+		Long: `Convert the loose configuration files (search terms, users tags, personas, events names,
+A/B test variants and recommend) to a single scenario file, usable with the --scenario flag.
+The scenario is written in YAML, or in JSON if the output file has a .json extension.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			io := iostreams.System()
+
+			s, err := scenario.Import(files)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				return s.Write(io.Out, true)
+			}
+			if err := s.Save(output); err != nil {
+				return err
+			}
+			if io.IsStdoutTTY() {
+				fmt.Fprintf(io.Out, "%s Scenario written to %s\n", io.ColorScheme().SuccessIcon(), output)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&files.SearchTerms, "search-terms", "searches.json", "searches terms file")
+	cmd.Flags().StringVar(&files.UserTags, "user-tags", "user-tags.json", "users tags file")
+	cmd.Flags().StringVar(&files.Personas, "personas", "personas.json", "users persona file")
+	cmd.Flags().StringVar(&files.EventsNames, "events-names", "events-names.json", "events names file")
+	cmd.Flags().StringVar(&files.ABTestVariants, "ab-test-variants", "", "A/B Test: variants targets file")
+	cmd.Flags().StringVar(&files.Recommend, "recommend", "", "recommend configuration file")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "scenario file to write (YAML, or JSON with a .json extension), stdout if empty")
+
+	return cmd
+}
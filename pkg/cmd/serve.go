@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+	"github.com/algolia/fake-insights-generator/pkg/utils"
+)
+
+// NewServeCmd creates and returns a serve command
+func NewServeCmd() *cobra.Command {
+	cfg := &events.Config{}
+	opts := &eventsOptions{}
+	var period time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Generate analytics events continuously, following a daily and weekly traffic shape",
+		Long: `Run as a long-lived process generating analytics events at the start of every period (see --period).
+The --users flag is the number of users per day: they are spread over the day and the week following the
+schedule, with more traffic in the evenings, less at night and peaks at the weekends. The personas follow
+the schedule as well, each of them running about once a day. The growth calendar
+of the schedule multiplies the number of users and the click-through and conversion rates over time,
+instead of the --growth flag and the scenario growth.`,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.InitializeConfig(cmd, "serve")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg.IO = iostreams.System()
+
+			schedule := events.NewSchedule()
+			if scheduleFileName := cmd.Flag("schedule").Value.String(); scheduleFileName != "" {
+				var err error
+				schedule, err = events.LoadSchedule(scheduleFileName)
+				if err != nil {
+					return err
+				}
+			}
+			if period < time.Minute {
+				return fmt.Errorf("the --period flag must be at least 1m")
+			}
+
+			if err := loadEventsScenario(cmd, cfg); err != nil {
+				return err
+			}
+			// The growth of a serve is the calendar of its schedule.
+			if cfg.Growth != nil || cmd.Flag("growth").Value.String() != "" {
+				return fmt.Errorf("the growth of the serve command is the calendar of the schedule: remove the --growth flag or the scenario growth")
+			}
+			cfg.Rand = utils.NewRand(opts.Seed)
+			if err := setupEvents(cmd, cfg, opts); err != nil {
+				return err
+			}
+			if _, err := openEventsOutputs(cmd, cfg); err != nil {
+				return err
+			}
+
+			// The serve stops on Ctrl-C or when the process is terminated.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			cs := cfg.IO.ColorScheme()
+			if cfg.DryRun {
+				fmt.Fprintf(cfg.IO.Out, "%s Dry run is ON: Events WILL NOT be sent to Insights\n", cs.WarningIcon())
+			}
+			fmt.Fprintf(cfg.IO.Out, "%s Serving about %d users per day, every %s (Ctrl-C to stop)\n",
+				cs.SuccessIcon(), cfg.NumberOfUsers, period)
+
+			err := events.Serve(ctx, cfg, schedule, period, func(tick events.Tick) {
+				printTick(cfg.IO, tick)
+			})
+			if closeErr := closeEventsOutputs(cfg); err == nil {
+				err = closeErr
+			}
+			return err
+		},
+	}
+
+	addEventsFlags(cmd, cfg, opts)
+	cmd.Flags().Lookup("users").Usage = "number of users per day"
+	_ = cmd.Flags().MarkHidden("growth")
+
+	cmd.Flags().String("schedule", "", "JSON schedule file: hours and weekdays traffic shapes, time zone and growth calendar (default shape if empty)")
+	cmd.Flags().DurationVar(&period, "period", 10*time.Minute, "period between two runs")
+
+	return cmd
+}
+
+// printTick prints the outcome of a period: its number of users, searches, clicks and conversions.
+func printTick(io *iostreams.IOStreams, tick events.Tick) {
+	cs := io.ColorScheme()
+
+	// The first stats are the stats of all the search terms.
+	searches, clicks, conversions := 0, 0, 0
+	if len(tick.Stats) > 0 {
+		searches = tick.Stats[0].TotalSearches
+		clicks = tick.Stats[0].Stats.TotalClicks()
+		conversions = tick.Stats[0].Stats.TotalConversions()
+	}
+	fmt.Fprintf(io.Out, "%s %d users, %d searches, %d clicks, %d conversions (growth x%.2f)\n",
+		tick.Time.Format("2006-01-02 15:04"), tick.Users, searches, clicks, conversions, tick.Growth)
+	if tick.Err != nil {
+		fmt.Fprintf(io.ErrOut, "%s %v\n", cs.FailureIcon(), tick.Err)
+	}
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/personalization"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+	"github.com/spf13/cobra"
+
+	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+	"github.com/algolia/fake-insights-generator/pkg/snapshot"
+	"github.com/algolia/fake-insights-generator/pkg/utils"
+)
+
+// defaultRegion is the region of the personalization strategy when none is given.
+const defaultRegion = "us"
+
+// NewSnapshotCmd creates and returns a snapshot command
+func NewSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save and restore the settings, rules, synonyms and personalization strategy of an index",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.InitializeConfig(cmd, "snapshot")
+		},
+	}
+
+	cmd.PersistentFlags().String("app-id", "", "Algolia application ID")
+	cmd.PersistentFlags().String("api-key", "", "Algolia API key")
+	cmd.PersistentFlags().String("index-name", "", "Algolia index name")
+	cmd.PersistentFlags().String("region", "", "region of the personalization strategy: us, eu or de")
+
+	cmd.AddCommand(newSnapshotSaveCmd())
+	cmd.AddCommand(newSnapshotRestoreCmd())
+
+	return cmd
+}
+
+func newSnapshotSaveCmd() *cobra.Command {
+	var sections []string
+
+	cmd := &cobra.Command{
+		Use:   "save <directory>",
+		Short: "Save a snapshot of an index to a directory",
+		Long: `Save a snapshot of an index to a directory: settings.json, rules.json, synonyms.json and
+perso-strategy.json (the personalization strategy of the application), with a snapshot.json manifest.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			io := iostreams.System()
+
+			appId := cmd.Flag("app-id").Value.String()
+			apiKey := cmd.Flag("api-key").Value.String()
+			indexName := cmd.Flag("index-name").Value.String()
+			if appId == "" || apiKey == "" || indexName == "" {
+				return fmt.Errorf("missing required flags: app-id, api-key, index-name")
+			}
+			regionName := cmd.Flag("region").Value.String()
+			if regionName == "" {
+				regionName = defaultRegion
+			}
+
+			_, persoRegion, err := utils.APIRegions(regionName)
+			if err != nil {
+				return err
+			}
+
+			index := search.NewClient(appId, apiKey).InitIndex(indexName)
+			perso := personalization.NewClient(appId, apiKey, persoRegion)
+
+			if io.IsStdoutTTY() {
+				io.StartProgressIndicatorWithLabel(fmt.Sprintf("Saving a snapshot of %s...", indexName))
+			}
+			s, err := snapshot.Take(index, perso, regionName, sections)
+			if err == nil {
+				err = s.Save(args[0])
+			}
+			if io.IsStdoutTTY() {
+				io.StopProgressIndicator()
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(io.Out, "%s Snapshot of %s saved to %s (%s)\n",
+				io.ColorScheme().SuccessIcon(), indexName, args[0], strings.Join(sections, ", "))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&sections, "sections", snapshot.Sections, "sections to save: settings, rules, synonyms, personalization")
+
+	return cmd
+}
+
+func newSnapshotRestoreCmd() *cobra.Command {
+	var dryRun bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "restore <directory>",
+		Short: "Restore a snapshot to an index",
+		Long: `Restore a snapshot to an index: the settings are set, the rules and the synonyms replace the
+existing ones, and the personalization strategy replaces the current one. The sections without a file
+in the snapshot directory are left untouched. The changes are shown, and confirmed, before being applied.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			io := iostreams.System()
+			cs := io.ColorScheme()
+
+			s, err := snapshot.Load(args[0])
+			if err != nil {
+				return err
+			}
+
+			appId := cmd.Flag("app-id").Value.String()
+			apiKey := cmd.Flag("api-key").Value.String()
+			indexName := cmd.Flag("index-name").Value.String()
+			if indexName == "" {
+				indexName = s.Manifest.Index
+			}
+			if appId == "" || apiKey == "" || indexName == "" {
+				return fmt.Errorf("missing required flags: app-id, api-key, index-name")
+			}
+			regionName := cmd.Flag("region").Value.String()
+			if regionName == "" {
+				regionName = s.Manifest.Region
+			}
+			if regionName == "" {
+				regionName = defaultRegion
+			}
+
+			_, persoRegion, err := utils.APIRegions(regionName)
+			if err != nil {
+				return err
+			}
+
+			index := search.NewClient(appId, apiKey).InitIndex(indexName)
+			perso := personalization.NewClient(appId, apiKey, persoRegion)
+
+			// Diff preview: the current configuration of the restored sections.
+			current, err := snapshot.Take(index, perso, regionName, snapshotSections(s))
+			if err != nil {
+				return err
+			}
+			changes, err := s.Diff(current)
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				fmt.Fprintf(io.Out, "%s %s is already up to date with the snapshot\n", cs.SuccessIcon(), indexName)
+				return nil
+			}
+			printChanges(io, changes)
+
+			if dryRun {
+				return nil
+			}
+			if !yes {
+				ok, err := confirm(io, fmt.Sprintf("Restore the snapshot to %s?", cs.Bold(indexName)))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Fprintf(io.Out, "%s Restore canceled\n", cs.WarningIcon())
+					return nil
+				}
+			}
+
+			if err := s.Restore(index, perso, snapshot.Replace); err != nil {
+				return err
+			}
+			fmt.Fprintf(io.Out, "%s Snapshot restored to %s\n", cs.SuccessIcon(), indexName)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show the changes, without restoring the snapshot")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "restore the snapshot without confirmation")
+
+	return cmd
+}
+
+// snapshotSections returns the sections of a snapshot.
+func snapshotSections(s *snapshot.Snapshot) []string {
+	var sections []string
+	if s.Settings != nil {
+		sections = append(sections, snapshot.SectionSettings)
+	}
+	if s.Rules != nil {
+		sections = append(sections, snapshot.SectionRules)
+	}
+	if s.Synonyms != nil {
+		sections = append(sections, snapshot.SectionSynonyms)
+	}
+	if s.PersoStrategy != nil {
+		sections = append(sections, snapshot.SectionPersonalization)
+	}
+	return sections
+}
+
+// printChanges prints the changes of a restoration, by section.
+func printChanges(io *iostreams.IOStreams, changes []snapshot.Change) {
+	cs := io.ColorScheme()
+	section := ""
+	for _, change := range changes {
+		if change.Section != section {
+			section = change.Section
+			fmt.Fprintln(io.Out, cs.Bold(section))
+		}
+		color := cs.Yellow
+		switch change.Type {
+		case snapshot.Added:
+			color = cs.Green
+		case snapshot.Removed:
+			color = cs.Red
+		}
+		fmt.Fprintf(io.Out, "  %s\n", color(change.String()))
+	}
+}
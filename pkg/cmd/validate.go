@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+	"github.com/spf13/cobra"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/iostreams"
+	"github.com/algolia/fake-insights-generator/pkg/utils"
+	"github.com/algolia/fake-insights-generator/pkg/validate"
+)
+
+type validateOptions struct {
+	IO *iostreams.IOStreams
+
+	Files validate.Files
+	Index validate.Index
+}
+
+// NewValidateCmd creates and returns a validate command
+func NewValidateCmd() *cobra.Command {
+	opts := &validateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the scenario files of the events command",
+		Long: `Validate the scenario files of the events command: unknown keys, wrong types and bad weights.
+With an Algolia index (or an offline records dump), the filter attributes and the search terms are also checked.`,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Same configuration as the events command.
+			return utils.InitializeConfig(cmd, "events")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.IO = iostreams.System()
+
+			// Like the events command, the sections of the scenario replace the files not set explicitly.
+			if opts.Files.Scenario != "" {
+				for name, file := range map[string]*string{
+					"search-terms": &opts.Files.SearchTerms,
+					"user-tags":    &opts.Files.UserTags,
+					"personas":     &opts.Files.Personas,
+					"events-names": &opts.Files.EventsNames,
+				} {
+					if !cmd.Flags().Changed(name) {
+						*file = ""
+					}
+				}
+			}
+
+			appId := cmd.Flag("app-id").Value.String()
+			apiKey := cmd.Flag("api-key").Value.String()
+			indexName := cmd.Flag("index-name").Value.String()
+			recordsFileName := cmd.Flag("records").Value.String()
+
+			if recordsFileName != "" {
+				localIndex, err := events.NewLocalIndex(indexName, recordsFileName)
+				if err != nil {
+					return err
+				}
+				opts.Index = &validate.LocalIndex{LocalIndex: localIndex}
+			} else if appId != "" && apiKey != "" && indexName != "" {
+				index, err := validate.NewAlgoliaIndex(search.NewClient(appId, apiKey).InitIndex(indexName))
+				if err != nil {
+					return err
+				}
+				opts.Index = index
+			}
+
+			return runValidateCmd(opts)
+		},
+	}
+
+	cmd.Flags().String("app-id", "", "Algolia application ID")
+	cmd.Flags().String("api-key", "", "Algolia API key")
+	cmd.Flags().String("index-name", "", "Algolia index name")
+	cmd.Flags().String("records", "", "offline mode: JSON records dump to check the search terms against, instead of the Algolia index")
+
+	cmd.Flags().StringVar(&opts.Files.Scenario, "scenario", "", "scenario file (YAML or JSON), instead of the files below")
+	cmd.Flags().StringVar(&opts.Files.SearchTerms, "search-terms", "searches.json", "searches terms file")
+	cmd.Flags().StringVar(&opts.Files.UserTags, "user-tags", "user-tags.json", "users tags file")
+	cmd.Flags().StringVar(&opts.Files.Personas, "personas", "personas.json", "users persona file")
+	cmd.Flags().StringVar(&opts.Files.EventsNames, "events-names", "events-names.json", "events names file")
+	cmd.Flags().StringVar(&opts.Files.ABTestVariants, "ab-test-variants", "", "A/B Test: variants targets file")
+
+	return cmd
+}
+
+func runValidateCmd(opts *validateOptions) error {
+	cs := opts.IO.ColorScheme()
+
+	report := validate.ValidateFiles(opts.Files)
+	if opts.Index != nil {
+		if opts.IO.IsStdoutTTY() {
+			opts.IO.StartProgressIndicatorWithLabel(fmt.Sprintf("Checking the search terms on %s...", opts.Index.GetName()))
+		}
+		err := report.ValidateIndex(opts.Index)
+		if opts.IO.IsStdoutTTY() {
+			opts.IO.StopProgressIndicator()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, issue := range report.Issues {
+		icon := cs.WarningIcon()
+		if issue.Severity == validate.SeverityError {
+			icon = cs.FailureIcon()
+		}
+		fmt.Fprintf(opts.IO.Out, "%s %s\n", icon, issue)
+	}
+
+	if report.Errors() > 0 {
+		return fmt.Errorf("%d errors, %d warnings", report.Errors(), report.Warnings())
+	}
+	if report.Warnings() > 0 {
+		fmt.Fprintf(opts.IO.Out, "%s Valid with %d warnings\n", cs.WarningIcon(), report.Warnings())
+	} else {
+		fmt.Fprintf(opts.IO.Out, "%s Valid!\n", cs.SuccessIcon())
+	}
+	if opts.Index == nil {
+		fmt.Fprintf(opts.IO.Out, "%s Use --records or the Algolia credentials to check the search terms and filters against the index\n", cs.WarningIcon())
+	}
+	return nil
+}
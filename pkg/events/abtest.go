@@ -0,0 +1,144 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/analytics"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+)
+
+// abTestStatusActive is the status of the running A/B tests in the Analytics API.
+const abTestStatusActive = "active"
+
+type ABTest struct {
+	// VariantID is the variant to favorize, with additive CTR / CVR bumps (in percent).
+	VariantID        int
+	ClickThroughRate float64
+	ConversionRate   float64
+
+	// Variants are the absolute targets of each variant, they take precedence over the bumps.
+	Variants ABTestVariants
+}
+
+// Enabled returns true if the events depend on the A/B test variant of the searches.
+func (t *ABTest) Enabled() bool {
+	return t.VariantID != 0 || len(t.Variants) > 0
+}
+
+// ABTestVariant holds the targets of an A/B test variant.
+// The variant is identified by its ID (1 for the A variant, 2 for the B variant...) or by its index name,
+// resolved with the running A/B test of the index. The targets left to 0 fall back to the search term
+// and global ones.
+type ABTestVariant struct {
+	ID               int     `json:"id,omitempty"`
+	Index            string  `json:"index,omitempty"`
+	ClickThroughRate float64 `json:"click_through_rate,omitempty"`
+	ConversionRate   float64 `json:"conversion_rate,omitempty"`
+	ClickPosition    int     `json:"click_position,omitempty"`
+}
+
+type ABTestVariants []ABTestVariant
+
+// LoadABTestVariants loads the variants targets from a JSON file.
+func LoadABTestVariants(fileName string) (ABTestVariants, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	bytes, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var variants ABTestVariants
+	if err := json.Unmarshal(bytes, &variants); err != nil {
+		return nil, err
+	}
+	for i, variant := range variants {
+		if variant.ID == 0 && variant.Index == "" {
+			return nil, fmt.Errorf("A/B test variant #%d: missing id or index", i)
+		}
+		if variant.ClickThroughRate < 0 || variant.ConversionRate < 0 || variant.ClickPosition < 0 {
+			return nil, fmt.Errorf("A/B test variant #%d: negative target", i)
+		}
+	}
+	return variants, nil
+}
+
+// Get returns the targets of a variant, or nil if the variant has none.
+func (v ABTestVariants) Get(id int) *ABTestVariant {
+	if id == 0 {
+		return nil
+	}
+	for i := range v {
+		if v[i].ID == id {
+			return &v[i]
+		}
+	}
+	return nil
+}
+
+// Resolve sets the ID of the variants identified by their index name, from the given A/B test.
+// Without a test, every variant must have an ID.
+func (v ABTestVariants) Resolve(test *analytics.ABTestResponse) error {
+	for i := range v {
+		if v[i].Index == "" {
+			continue
+		}
+		if test == nil {
+			if v[i].ID == 0 {
+				return fmt.Errorf("A/B test variant %s is identified by its index: the variant ID is needed, or the A/B test discovery", v[i].Index)
+			}
+			continue
+		}
+		id := 0
+		for j, variant := range test.Variants {
+			if variant.Index == v[i].Index {
+				// The variant IDs returned with the search results are the positions of the variants in the test.
+				id = j + 1
+				break
+			}
+		}
+		if id == 0 {
+			return fmt.Errorf("index %s is not a variant of the A/B test %q", v[i].Index, test.Name)
+		}
+		v[i].ID = id
+	}
+	return nil
+}
+
+// ABTestsLister lists the A/B tests of an application.
+// It is implemented by `*analytics.Client`.
+type ABTestsLister interface {
+	GetABTests(opts ...interface{}) (analytics.GetABTestsRes, error)
+}
+
+// DiscoverABTest returns the running A/B test of an index: the active test with one of its variants on the index.
+func DiscoverABTest(client ABTestsLister, indexName string) (*analytics.ABTestResponse, error) {
+	offset := 0
+	for {
+		res, err := client.GetABTests(opt.Offset(offset), opt.Limit(100))
+		if err != nil {
+			return nil, err
+		}
+		for i, test := range res.ABTests {
+			if test.Status != abTestStatusActive {
+				continue
+			}
+			for _, variant := range test.Variants {
+				if variant.Index == indexName {
+					return &res.ABTests[i], nil
+				}
+			}
+		}
+		offset += len(res.ABTests)
+		if len(res.ABTests) == 0 || offset >= res.Total {
+			return nil, fmt.Errorf("no running A/B test found for index %s", indexName)
+		}
+	}
+}
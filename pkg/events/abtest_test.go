@@ -0,0 +1,117 @@
+package events
+
+import (
+	"math"
+	"testing"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/analytics"
+)
+
+// fakeABTestsLister returns the A/B tests by pages of 2.
+type fakeABTestsLister struct {
+	tests []analytics.ABTestResponse
+	calls int
+}
+
+func (l *fakeABTestsLister) GetABTests(opts ...interface{}) (analytics.GetABTestsRes, error) {
+	start := l.calls * 2
+	l.calls++
+	end := start + 2
+	if end > len(l.tests) {
+		end = len(l.tests)
+	}
+	return analytics.GetABTestsRes{ABTests: l.tests[start:end], Count: end - start, Total: len(l.tests)}, nil
+}
+
+func newABTestResponse(name string, status string, indices ...string) analytics.ABTestResponse {
+	test := analytics.ABTestResponse{Name: name, Status: status}
+	for _, index := range indices {
+		test.Variants = append(test.Variants, analytics.VariantResponse{Index: index})
+	}
+	return test
+}
+
+func TestDiscoverABTest(t *testing.T) {
+	lister := &fakeABTestsLister{tests: []analytics.ABTestResponse{
+		newABTestResponse("old", "stopped", "products", "products_old"),
+		newABTestResponse("other", "active", "articles", "articles_new"),
+		newABTestResponse("ranking", "active", "products", "products_ranking"),
+	}}
+
+	tests := []struct {
+		name     string
+		index    string
+		wantTest string
+		wantErr  bool
+	}{
+		{name: "A variant", index: "products", wantTest: "ranking"},
+		{name: "B variant", index: "products_ranking", wantTest: "ranking"},
+		{name: "stopped test", index: "products_old", wantErr: true},
+		{name: "no test", index: "customers", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lister.calls = 0
+			test, err := DiscoverABTest(lister, tt.index)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiscoverABTest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && test.Name != tt.wantTest {
+				t.Errorf("DiscoverABTest() = %s, want %s", test.Name, tt.wantTest)
+			}
+		})
+	}
+}
+
+func TestABTestVariants_Resolve(t *testing.T) {
+	test := newABTestResponse("ranking", "active", "products", "products_ranking", "products_popularity")
+	variants := ABTestVariants{{ID: 1}, {Index: "products_popularity"}, {Index: "products_ranking"}}
+	if err := variants.Resolve(&test); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, want := range []int{1, 3, 2} {
+		if variants[i].ID != want {
+			t.Errorf("variant #%d: expected ID %d, got %d", i, want, variants[i].ID)
+		}
+	}
+
+	if err := (ABTestVariants{{Index: "products_unknown"}}).Resolve(&test); err == nil {
+		t.Errorf("expected an error for an index not in the A/B test")
+	}
+	if err := (ABTestVariants{{Index: "products_ranking"}}).Resolve(nil); err == nil {
+		t.Errorf("expected an error for a variant without ID nor A/B test")
+	}
+}
+
+func TestSearchEvent_Rates_ABTest(t *testing.T) {
+	cfg := &Config{ClickThroughRate: 20, ConversionRate: 10}
+	cfg.ABTest = ABTest{
+		VariantID:        1,
+		ClickThroughRate: 5,
+		ConversionRate:   2,
+		Variants:         ABTestVariants{{ID: 2, ClickThroughRate: 30}, {ID: 3, ConversionRate: 4}},
+	}
+
+	tests := []struct {
+		name      string
+		variantID int
+		wantCTR   float64
+		wantCVR   float64
+	}{
+		{name: "no A/B test", variantID: 0, wantCTR: 0.20, wantCVR: 0.10},
+		{name: "favorized variant", variantID: 1, wantCTR: 0.25, wantCVR: 0.12},
+		{name: "variant with CTR target", variantID: 2, wantCTR: 0.30, wantCVR: 0.10},
+		{name: "variant with CVR target", variantID: 3, wantCTR: 0.20, wantCVR: 0.04},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &SearchEvent{ABTestVariantID: tt.variantID}
+			if ctr := e.ClickThroughRate(cfg); math.Abs(ctr-tt.wantCTR) > 1e-9 {
+				t.Errorf("SearchEvent.ClickThroughRate() = %v, want %v", ctr, tt.wantCTR)
+			}
+			if cvr := e.ConversionRate(cfg); math.Abs(cvr-tt.wantCVR) > 1e-9 {
+				t.Errorf("SearchEvent.ConversionRate() = %v, want %v", cvr, tt.wantCVR)
+			}
+		})
+	}
+}
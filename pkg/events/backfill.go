@@ -0,0 +1,82 @@
+package events
+
+import (
+	"math/rand"
+	"time"
+
+	wr "github.com/mroth/weightedrand"
+)
+
+const (
+	// InsightsMaxEventAge is the maximum age of an event accepted by the Insights API.
+	InsightsMaxEventAge = 4 * 24 * time.Hour
+	// insightsEventAgeMargin is kept below InsightsMaxEventAge when sending events, so the events
+	// generated before a long run (e.g. the recommend events) don't expire before they are sent.
+	insightsEventAgeMargin = time.Hour
+
+	day = 24 * time.Hour
+)
+
+// weekdayTraffic is the relative amount of traffic for each day of the week (Sunday first).
+var weekdayTraffic = [7]float64{1.2, 0.9, 0.9, 0.95, 1, 1.1, 1.3}
+
+// Window is a historical time window the generated traffic is spread over.
+type Window struct {
+	From time.Time
+	To   time.Time
+
+	Chooser *wr.Chooser
+}
+
+// NewWindow returns a Window between from and to, with a per-day traffic curve.
+// The end of the window is capped to the current time.
+func NewWindow(r *rand.Rand, from time.Time, to time.Time) (*Window, error) {
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	w := &Window{From: from, To: to}
+	if err := w.NewChooser(r); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewChooser builds the per-day traffic curve of the window.
+// Each day is weighted by its day of the week, with some noise so the curve doesn't look too regular.
+func (w *Window) NewChooser(r *rand.Rand) error {
+	choices := make([]wr.Choice, 0)
+	for d := w.From; d.Before(w.To); d = d.Add(day) {
+		weight := weekdayTraffic[d.Weekday()] * (0.85 + r.Float64()*0.3)
+		choices = append(choices, wr.Choice{
+			Item:   d,
+			Weight: uint(weight * 100),
+		})
+	}
+	chooser, err := wr.NewChooser(choices...)
+	if err != nil {
+		return err
+	}
+	w.Chooser = chooser
+	return nil
+}
+
+// PickTime returns a random time inside the window, following the per-day traffic curve.
+func (w *Window) PickTime(r *rand.Rand) time.Time {
+	start := w.Chooser.PickSource(r).(time.Time)
+	end := start.Add(day)
+	if end.After(w.To) {
+		end = w.To
+	}
+	return start.Add(time.Duration(r.Int63n(int64(end.Sub(start)))))
+}
+
+// IsOutOfRange returns true if the window starts before the Insights API max event age.
+func (w *Window) IsOutOfRange() bool {
+	return time.Since(w.From) > InsightsMaxEventAge
+}
+
+// IsTooOld returns true if an event with the given timestamp would be rejected by the Insights API,
+// or is about to be: the events older than InsightsMaxEventAge minus a margin are too old.
+func IsTooOld(timestamp time.Time) bool {
+	return time.Since(timestamp) > InsightsMaxEventAge-insightsEventAgeMargin
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint records the progress of a run: the number of sessions done for each user,
+// once their events have been written to the sink. A run can be resumed from it
+// with the same seed, so the same users are generated again and only the remaining
+// sessions are run.
+type Checkpoint struct {
+	mu       sync.Mutex
+	fileName string
+
+	Seed          int64 `json:"seed"`
+	NumberOfUsers int   `json:"users"`
+	// GrownUsers is the number of users of the run, once grown at its start (see Growth.Users):
+	// a resumed run generates the same users, whenever it is resumed.
+	GrownUsers      int         `json:"grown_users,omitempty"`
+	SearchesPerUser int         `json:"searches_per_user"`
+	Sessions        map[int]int `json:"sessions"`
+}
+
+// NewCheckpoint returns an empty checkpoint for a run of grownUsers users, saved to the given file.
+func NewCheckpoint(fileName string, seed int64, cfg *Config, grownUsers int) *Checkpoint {
+	return &Checkpoint{
+		fileName:        fileName,
+		Seed:            seed,
+		NumberOfUsers:   cfg.NumberOfUsers,
+		GrownUsers:      grownUsers,
+		SearchesPerUser: cfg.SearchesPerUser,
+		Sessions:        make(map[int]int),
+	}
+}
+
+// LoadCheckpoint reads a checkpoint file saved by a previous run.
+func LoadCheckpoint(fileName string) (*Checkpoint, error) {
+	bytes, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &Checkpoint{fileName: fileName}
+	if err := json.Unmarshal(bytes, checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file %s: %v", fileName, err)
+	}
+	if checkpoint.Sessions == nil {
+		checkpoint.Sessions = make(map[int]int)
+	}
+	return checkpoint, nil
+}
+
+// Check returns an error if the run configuration doesn't match the checkpoint's one.
+func (c *Checkpoint) Check(cfg *Config) error {
+	if c.NumberOfUsers != cfg.NumberOfUsers || c.SearchesPerUser != cfg.SearchesPerUser {
+		return fmt.Errorf("the checkpoint was saved for %d users with %d searches each, not %d users with %d searches",
+			c.NumberOfUsers, c.SearchesPerUser, cfg.NumberOfUsers, cfg.SearchesPerUser)
+	}
+	return nil
+}
+
+// Users returns the number of users of the run, grown at its start.
+// The checkpoints saved without it were saved for runs without growth.
+func (c *Checkpoint) Users() int {
+	if c.GrownUsers == 0 {
+		return c.NumberOfUsers
+	}
+	return c.GrownUsers
+}
+
+// SessionsDone returns the number of sessions already done for a user.
+func (c *Checkpoint) SessionsDone(user int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Sessions[user]
+}
+
+// Add records a session done for each of the given users (a user can appear several times).
+func (c *Checkpoint) Add(users []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, user := range users {
+		c.Sessions[user]++
+	}
+}
+
+// Save writes the checkpoint file. The file is replaced atomically,
+// so an interruption never leaves a truncated checkpoint.
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	bytes, err := json.Marshal(c)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(c.fileName), filepath.Base(c.fileName)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.fileName)
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+// cancelingSearcher cancels the run after a given number of searches.
+type cancelingSearcher struct {
+	Searcher
+	mu     sync.Mutex
+	calls  int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (s *cancelingSearcher) Search(query string, opts ...interface{}) (search.QueryRes, error) {
+	s.mu.Lock()
+	s.calls++
+	if s.calls == s.after {
+		s.cancel()
+	}
+	s.mu.Unlock()
+	return s.Searcher.Search(query, opts...)
+}
+
+func TestRun_Resume(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	// Interrupted run
+	cfg, _ := newTestConfig(t, 1)
+	cfg.Checkpoint = NewCheckpoint(fileName, 1, cfg, cfg.NumberOfUsers)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg.SearchIndex = &cancelingSearcher{Searcher: cfg.SearchIndex, after: 60, cancel: cancel}
+	stats, err := Run(ctx, cfg)
+	if err != context.Canceled {
+		t.Fatalf("expected the run to be canceled, got %v", err)
+	}
+	interruptedSearches := stats[0].TotalSearches
+	if interruptedSearches == 0 || interruptedSearches >= cfg.NumberOfUsers*cfg.SearchesPerUser {
+		t.Fatalf("expected a partial run, got %d searches", interruptedSearches)
+	}
+
+	// Resumed run
+	checkpoint, err := LoadCheckpoint(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg, _ = newTestConfig(t, checkpoint.Seed)
+	if err := checkpoint.Check(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checkpoint.Users() != cfg.NumberOfUsers {
+		t.Errorf("expected the checkpoint to be saved for %d users, got %d", cfg.NumberOfUsers, checkpoint.Users())
+	}
+	cfg.Checkpoint = checkpoint
+	stats, err = Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total := interruptedSearches + stats[0].TotalSearches; total != cfg.NumberOfUsers*cfg.SearchesPerUser {
+		t.Errorf("expected %d sessions in total, got %d", cfg.NumberOfUsers*cfg.SearchesPerUser, total)
+	}
+
+	checkpoint, err = LoadCheckpoint(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for user := 0; user < cfg.NumberOfUsers; user++ {
+		if done := checkpoint.SessionsDone(user); done != cfg.SearchesPerUser {
+			t.Errorf("user #%d: expected %d sessions done, got %d", user, cfg.SearchesPerUser, done)
+		}
+	}
+}
@@ -1,25 +1,23 @@
 package events
 
 import (
+	"math/rand"
 	"time"
 
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
-	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
 	"github.com/algolia/fake-insights-generator/pkg/iostreams"
 )
 
-type ABTest struct {
-	VariantID        int
-	ClickThroughRate float64
-	ConversionRate   float64
-}
-
 type Config struct {
 	IO     *iostreams.IOStreams
 	DryRun bool
 
-	SearchIndex    *search.Index
+	// Rand is the source of all the random choices, seeded with the --seed flag for reproducible runs.
+	Rand *rand.Rand
+
+	SearchIndex    Searcher
 	InsightsClient *insights.Client
+	Sink           EventSink
 
 	SearchTerms    *SearchTerms
 	TagsCollection []TagsCollection
@@ -27,6 +25,7 @@ type Config struct {
 	NumberOfUsers   int
 	SearchesPerUser int
 	SearchDelay     time.Duration
+	Concurrency     int
 	PersonaUsers    []*User
 	EventsNames     EventNames
 
@@ -35,7 +34,36 @@ type Config struct {
 	ClickThroughRate float64
 	ConversionRate   float64
 
-	AcceleratorOrigin *time.Time
+	// Session follow-up searches, in percent of the sessions.
+	RefinementRate float64
+	PaginationRate float64
+
+	// ViewRate is the percentage of sessions sending view events for the hits seen.
+	ViewRate float64
+
+	// Typing: percentage of the sessions where the query is typed in a search-as-you-type UI
+	// (one search per keystroke), with a typo in TypoRate percent of the typed queries.
+	TypingRate     float64
+	TypoRate       float64
+	KeystrokeDelay time.Duration
+
+	// QueryVariations mutate the queries (typos, plurals...), unless the search term has its own.
+	QueryVariations QueryVariations
+
+	// Growth is the growth of the number of users and of the click-through and conversion rates
+	// over time, for the search terms without their own.
+	Growth *Growth
+
+	// Backfill mode: events are spread over a historical time window.
+	// Events too old for the Insights API are written to BackfillFile (CSV) instead, if defined.
+	Window       *Window
+	BackfillFile string
 
 	ABTest ABTest
+
+	// Checkpoint records the progress of the run, to resume it after an interruption.
+	Checkpoint *Checkpoint
+
+	// Tokens records the user tokens of the events, to delete their data with the cleanup command.
+	Tokens *TokenRecorder
 }
@@ -4,28 +4,29 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 
 	wr "github.com/mroth/weightedrand"
 )
 
 type EventNames map[string]map[string]int
 
-func (n EventNames) PickForType(eventType string) (string, error) {
+func (n EventNames) PickForType(r *rand.Rand, eventType string) (string, error) {
 	if len(n) == 0 || len(n[eventType]) == 0 {
 		return "", fmt.Errorf("no event names for the type \"%s\" found", eventType)
 	}
 	choices := make([]wr.Choice, 0, len(n))
-	for k, w := range n[eventType] {
+	for _, k := range sortedWeightKeys(n[eventType]) {
 		choices = append(choices, wr.Choice{
 			Item:   k,
-			Weight: uint(w),
+			Weight: uint(n[eventType][k]),
 		})
 	}
 	chooser, err := wr.NewChooser(choices...)
 	if err != nil {
 		return "", err
 	}
-	return chooser.Pick().(string), nil
+	return chooser.PickSource(r).(string), nil
 }
 
 func EventNamesFromFile(filename string) (EventNames, error) {
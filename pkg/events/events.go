@@ -1,10 +1,10 @@
 package events
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
-	"sort"
 	"sync"
 	"time"
 
@@ -14,20 +14,36 @@ import (
 
 const (
 	clickDistributionApogee = 10
+
+	// insightsMaxObjectIDs is the maximum number of objectIDs per Insights event.
+	insightsMaxObjectIDs = 20
 )
 
 type SearchEvent struct {
 	Term            SearchTerm
+	Query           string
+	SearchFilters   string
+	Page            int
 	ObjectIDs       []string
 	QueryID         string
 	Filters         []string
 	ABTestVariantID int
+	// Time is the time of the search, the rates growing over time.
+	Time time.Time
+
+	// FollowUp is true for the pagination and refinement searches of a session.
+	// They are not counted as searches in the stats.
+	FollowUp bool
 }
 
 // Event is a wrapper around an event to be sent to Insights.
 type Event struct {
 	InsightEvent *insights.Event
 	SearchEvent  *SearchEvent
+
+	// EndOfSession marks the end of a session of the user, after all its events.
+	// It is only used to checkpoint the progress of the run.
+	EndOfSession *User
 }
 
 func (e *Event) EventType() string {
@@ -47,14 +63,19 @@ func CalculatePositionWeight(itemPosition int, clickPosition int) uint {
 }
 
 // PickObjectIDPosition return the click position for a given searchEvent.
-// The position is picked based on the Term's click position first if defined, then on the global click position.
-func (e *SearchEvent) PickObjectIDPosition(cfg *Config) (int, error) {
+// The position is picked based on the A/B test variant's click position first if defined,
+// then on the Term's click position, then on the global click position.
+func (e *SearchEvent) PickObjectIDPosition(cfg *Config, r *rand.Rand) (int, error) {
+	clickPosition := cfg.ClickPosition
+	if e.Term.ClickPosition != 0 {
+		clickPosition = e.Term.ClickPosition
+	}
+	if variant := cfg.ABTest.Variants.Get(e.ABTestVariantID); variant != nil && variant.ClickPosition != 0 {
+		clickPosition = variant.ClickPosition
+	}
+
 	var choices []wr.Choice
 	for i := range e.ObjectIDs {
-		clickPosition := e.Term.ClickPosition
-		if clickPosition == 0 {
-			clickPosition = cfg.ClickPosition
-		}
 		choices = append(choices, wr.Choice{
 			Weight: CalculatePositionWeight(i+1, clickPosition),
 			Item:   i,
@@ -65,36 +86,64 @@ func (e *SearchEvent) PickObjectIDPosition(cfg *Config) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return chooser.Pick().(int), nil
+	return chooser.PickSource(r).(int), nil
 }
 
-// MaybeClickEvent returns a click event if the user clicked on a object from a SearchEvent.
-func MaybeClickEvent(user *User, cfg *Config, time time.Time, searchEvent SearchEvent) *Event {
-	// Get the click through rate for this specific search term
-	clickThroughRate := cfg.ClickThroughRate / 100
-	if searchEvent.Term.ClickThroughRate != 0 {
-		clickThroughRate = searchEvent.Term.ClickThroughRate / 100
+// ClickThroughRate returns the probability of a click on the SearchEvent.
+// The rate is based on the A/B test variant's click through rate first if defined,
+// then on the Term's click through rate, then on the global click through rate,
+// grown by the Term's growth or the global growth at the time of the search.
+func (e *SearchEvent) ClickThroughRate(cfg *Config) float64 {
+	if variant := cfg.ABTest.Variants.Get(e.ABTestVariantID); variant != nil && variant.ClickThroughRate != 0 {
+		return variant.ClickThroughRate / 100
+	}
+
+	clickThroughRate := cfg.ClickThroughRate
+	if e.Term.ClickThroughRate != 0 {
+		clickThroughRate = e.Term.ClickThroughRate
+	}
+	if growth := cfg.termGrowth(&e.Term); growth != nil {
+		clickThroughRate = growth.ClickThroughRate(clickThroughRate, e.Time)
 	}
+	clickThroughRate = clickThroughRate / 100
 
 	// Improve the click through rate if A/B test is enabled and the variant is the "good" one.
-	if searchEvent.ABTestVariantID != 0 && searchEvent.ABTestVariantID == cfg.ABTest.VariantID {
+	if e.ABTestVariantID != 0 && e.ABTestVariantID == cfg.ABTest.VariantID {
 		clickThroughRate = clickThroughRate + cfg.ABTest.ClickThroughRate/100
 	}
+	return math.Min(clickThroughRate, 1)
+}
 
-	if rand.Float64() > clickThroughRate {
-		return nil
+// ConversionRate returns the probability of a conversion on the SearchEvent.
+// The rate is based on the A/B test variant's conversion rate first if defined,
+// then on the Term's conversion rate, then on the global conversion rate,
+// grown by the Term's growth or the global growth at the time of the search.
+func (e *SearchEvent) ConversionRate(cfg *Config) float64 {
+	if variant := cfg.ABTest.Variants.Get(e.ABTestVariantID); variant != nil && variant.ConversionRate != 0 {
+		return variant.ConversionRate / 100
 	}
 
-	// Pick a random object ID to click on.
-	position, err := searchEvent.PickObjectIDPosition(cfg)
-	if err != nil {
-		return nil
+	conversionRate := cfg.ConversionRate
+	if e.Term.ConversionRate != 0 {
+		conversionRate = e.Term.ConversionRate
+	}
+	if growth := cfg.termGrowth(&e.Term); growth != nil {
+		conversionRate = growth.ConversionRate(conversionRate, e.Time)
 	}
-	objectID := searchEvent.ObjectIDs[position]
+	conversionRate = conversionRate / 100
 
-	eventName, err := cfg.EventsNames.PickForType(insights.EventTypeClick)
+	// Improve the conversion rate if A/B test is enabled and the variant is the "good" one.
+	if e.ABTestVariantID != 0 && e.ABTestVariantID == cfg.ABTest.VariantID {
+		conversionRate = conversionRate + cfg.ABTest.ConversionRate/100
+	}
+	return math.Min(conversionRate, 1)
+}
+
+// NewClickEvent returns a click event on the object at the given index of a SearchEvent.
+func NewClickEvent(user *User, cfg *Config, time time.Time, searchEvent *SearchEvent, index int) (*Event, error) {
+	eventName, err := cfg.EventsNames.PickForType(user.Rand, insights.EventTypeClick)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	insightsEvent := &insights.Event{
@@ -103,48 +152,54 @@ func MaybeClickEvent(user *User, cfg *Config, time time.Time, searchEvent Search
 		Index:     cfg.SearchIndex.GetName(),
 		UserToken: user.Token,
 		Timestamp: time,
-		ObjectIDs: []string{objectID},
-		Positions: []int{position + 1},
+		ObjectIDs: []string{searchEvent.ObjectIDs[index]},
+		Positions: []int{searchEvent.Page*cfg.HitsPerPage + index + 1},
 		QueryID:   searchEvent.QueryID,
 		Filters:   searchEvent.Filters,
 	}
 
 	return &Event{
 		InsightEvent: insightsEvent,
-		SearchEvent:  &searchEvent,
-	}
+		SearchEvent:  searchEvent,
+	}, nil
 }
 
-// MaybeConversionEvent returns a conversion event if the user converted on a object from a SearchEvent.
-func MaybeConversionEvent(user *User, cfg *Config, time time.Time, searchEvent SearchEvent) *Event {
-	// Global conversion rate
-	conversionRate := cfg.ConversionRate / 100
-
-	// Get the conversion rate for this specific search term
-	if searchEvent.Term.ConversionRate != 0 {
-		conversionRate = searchEvent.Term.ConversionRate / 100
-	}
-
-	// Improve the conversion rate if A/B test is enabled and the variant is the "good" one.
-	if searchEvent.ABTestVariantID != 0 && searchEvent.ABTestVariantID == cfg.ABTest.VariantID {
-		conversionRate = conversionRate + cfg.ABTest.ConversionRate/100
-	}
+// NewViewEvents returns the view events of the objects of a SearchEvent,
+// batched by the maximum number of objectIDs per Insights event.
+func NewViewEvents(user *User, cfg *Config, time time.Time, searchEvent *SearchEvent) ([]Event, error) {
+	events := make([]Event, 0)
+	for i := 0; i < len(searchEvent.ObjectIDs); i += insightsMaxObjectIDs {
+		end := i + insightsMaxObjectIDs
+		if end > len(searchEvent.ObjectIDs) {
+			end = len(searchEvent.ObjectIDs)
+		}
 
-	if rand.Float64() > conversionRate {
-		return nil
-	}
+		eventName, err := cfg.EventsNames.PickForType(user.Rand, insights.EventTypeView)
+		if err != nil {
+			return nil, err
+		}
 
-	// Pick a random object ID to convert.
-	position, err := searchEvent.PickObjectIDPosition(cfg)
-	if err != nil {
-		return nil
+		events = append(events, Event{
+			InsightEvent: &insights.Event{
+				EventType: insights.EventTypeView,
+				EventName: eventName,
+				Index:     cfg.SearchIndex.GetName(),
+				UserToken: user.Token,
+				Timestamp: time,
+				ObjectIDs: searchEvent.ObjectIDs[i:end],
+				Filters:   searchEvent.Filters,
+			},
+			SearchEvent: searchEvent,
+		})
 	}
-	objectID := searchEvent.ObjectIDs[position]
+	return events, nil
+}
 
-	// Pick a conversion event name.
-	eventName, err := cfg.EventsNames.PickForType(insights.EventTypeConversion)
+// NewConversionEvent returns a conversion event on the object at the given index of a SearchEvent.
+func NewConversionEvent(user *User, cfg *Config, time time.Time, searchEvent *SearchEvent, index int) (*Event, error) {
+	eventName, err := cfg.EventsNames.PickForType(user.Rand, insights.EventTypeConversion)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	insightsEvent := &insights.Event{
@@ -153,81 +208,131 @@ func MaybeConversionEvent(user *User, cfg *Config, time time.Time, searchEvent S
 		Index:     cfg.SearchIndex.GetName(),
 		UserToken: user.Token,
 		Timestamp: time,
-		ObjectIDs: []string{objectID},
+		ObjectIDs: []string{searchEvent.ObjectIDs[index]},
 		QueryID:   searchEvent.QueryID,
 		Filters:   searchEvent.Filters,
 	}
 
 	return &Event{
 		InsightEvent: insightsEvent,
-		SearchEvent:  &searchEvent,
-	}
+		SearchEvent:  searchEvent,
+	}, nil
+}
+
+// SinkBatchSize is the number of events written at once to the sink, as they are generated.
+const SinkBatchSize = 1000
+
+// defaultConcurrency is the default number of users generating events concurrently.
+const defaultConcurrency = 100
+
+// SessionErrors counts the sessions which failed (after the search retries), keeping the last error.
+type SessionErrors struct {
+	mu    sync.Mutex
+	Count int
+	Last  error
+}
+
+func (e *SessionErrors) Add(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Count++
+	e.Last = err
 }
 
 // GenerateEventsForAllUsers generates events for all users.
-// We create a pool of 100 goroutines to limit the number of concurrent requests.
-func GenerateEventsForAllUsers(wg *sync.WaitGroup, cfg *Config, users <-chan *User, events chan<- Event) {
-	for i := 0; i < 1000; i++ {
+// We create a pool of `cfg.Concurrency` goroutines to limit the number of concurrent requests.
+func GenerateEventsForAllUsers(ctx context.Context, wg *sync.WaitGroup, cfg *Config, users <-chan *User, events chan<- Event, sessionErrors *SessionErrors) {
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for user := range users {
-				GenerateEvents(wg, cfg, user, events)
+				GenerateEvents(ctx, wg, cfg, user, events, sessionErrors)
 			}
 		}()
 	}
 }
 
-// GenerateEvents generates events for a given user.
-func GenerateEvents(wg *sync.WaitGroup, cfg *Config, user *User, events chan<- Event) {
-	for i := 0; i < cfg.SearchesPerUser; i++ {
-		searchEvent, err := user.Search(cfg)
-		if err != nil {
-			fmt.Printf("Error doing search: %v\n", err)
-			continue
+// SessionStart returns the start time of a user session.
+// In backfill mode, the start time is picked inside the configured window.
+func (cfg *Config) SessionStart(r *rand.Rand) time.Time {
+	if cfg.Window == nil {
+		return time.Now()
+	}
+	return cfg.Window.PickTime(r)
+}
+
+// GenerateEvents generates events for a given user, one session per search.
+// It stops after the current session when the context is canceled.
+// When resuming from a checkpoint, only the remaining sessions of the user are run.
+func GenerateEvents(ctx context.Context, wg *sync.WaitGroup, cfg *Config, user *User, events chan<- Event, sessionErrors *SessionErrors) {
+	begin := time.Now()
+	start := cfg.SessionStart(user.Rand)
+	first := 0
+	if cfg.Checkpoint != nil {
+		first = cfg.Checkpoint.SessionsDone(user.Index)
+	}
+	for i := first; i < cfg.SearchesPerUser && ctx.Err() == nil; i++ {
+		session := &Session{
+			User:  user,
+			Cfg:   cfg,
+			Start: start.Add(time.Since(begin)),
 		}
-		events <- Event{SearchEvent: searchEvent}
-		if len(searchEvent.ObjectIDs) == 0 {
-			// fmt.Printf("Warning: No results for search term: %s - filters: %v - synonyms: %v\n", searchEvent.Term.Term, searchEvent.Filters, searchEvent.Term.Synonyms)
-			continue
+		sessionEvents, err := session.Run(ctx)
+		if cfg.Window == nil {
+			endBy(sessionEvents, time.Now())
 		}
-
-		// Generate a click event
-		clickEvent := MaybeClickEvent(user, cfg, time.Now(), *searchEvent)
-		if clickEvent != nil {
-			events <- *clickEvent
+		interrupted := err != nil && ctx.Err() != nil
+		if interrupted && len(sessionEvents) == 0 {
+			// Nothing happened: the session will be run again when resuming.
+			return
 		}
-
-		// Generate a conversion event
-		conversionEvent := MaybeConversionEvent(user, cfg, time.Now(), *searchEvent)
-		if conversionEvent != nil {
-			events <- *conversionEvent
+		for _, event := range sessionEvents {
+			events <- event
+		}
+		events <- Event{EndOfSession: user}
+		if interrupted {
+			return
+		}
+		if err != nil {
+			sessionErrors.Add(err)
+			continue
 		}
 
 		// Delay the next search to avoid triggering unwanted synonyms.
 		if i < cfg.SearchesPerUser-1 {
-			time.Sleep(time.Duration(cfg.SearchDelay * time.Second))
+			if err := sleep(ctx, time.Duration(cfg.SearchDelay*time.Second)); err != nil {
+				return
+			}
 		}
 	}
 }
 
 // Run is the entry point to generate the events.
-func Run(cfg *Config) (StatsPerTermList, error) {
-	// If we have an AcceleratorOrigin defined, modify the NumberOfUsers, ClickThroughRate and ConversionRate
-	if cfg.AcceleratorOrigin != nil {
-		daysSince := time.Since(*cfg.AcceleratorOrigin).Hours() / 24
-		accelerator := 1 + daysSince/200
-		cfg.ClickThroughRate = cfg.ClickThroughRate * accelerator
-		cfg.NumberOfUsers = int(math.Round(float64(cfg.NumberOfUsers) * accelerator))
-		cfg.ConversionRate = cfg.ConversionRate * accelerator
-		// fmt.Println("Accelerator:", cfg.NumberOfUsers, cfg.ClickThroughRate, cfg.ConversionRate)
+// If the context is canceled, the users stop after their current session: the events
+// generated so far are still written to the sink, and the stats are returned along with
+// the context error. The generation stops as well on the first error writing the events.
+// cfg.NumberOfUsers is the number of users of the run: the growth of the number of users is applied
+// by the caller (see Growth.Users), the rates grow at the time of each search (see SearchEvent.ClickThroughRate).
+func Run(ctx context.Context, cfg *Config) (StatsPerTermList, error) {
+	if cfg.Rand == nil {
+		cfg.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
 
+	// The generation is canceled on the first write error, so no more searches are run for nothing.
+	generateCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var wg sync.WaitGroup
-	users := GenerateUsers(&wg, cfg)
+	users := GenerateUsers(generateCtx, &wg, cfg)
 
 	events := make(chan Event)
-	GenerateEventsForAllUsers(&wg, cfg, users, events)
+	sessionErrors := &SessionErrors{}
+	GenerateEventsForAllUsers(generateCtx, &wg, cfg, users, events, sessionErrors)
 
 	// Wait for all goroutines to finish and close the results channel.
 	go func() {
@@ -235,35 +340,54 @@ func Run(cfg *Config) (StatsPerTermList, error) {
 		close(events)
 	}()
 
-	// Gather all the events created.
-	eventsList := make([]Event, 0)
-	for event := range events {
-		eventsList = append(eventsList, event)
-	}
-
-	// Compute the stats for each search term.
-	stats := make(StatsPerTermList, 0)
-	stats = append(stats, NewStatsForTerm("ALL", eventsList))
-	for _, term := range cfg.SearchTerms.SearchTerms {
-		stats = append(stats, NewStatsForTerm(term.Term, eventsList))
+	// Stream the events to the sink by batches, while computing the stats for each search term.
+	// The checkpoint is saved once the events of the sessions done are written.
+	stats := NewStatsCollector(cfg.SearchTerms.SearchTerms)
+	batch := make([]insights.Event, 0, SinkBatchSize)
+	sessionsDone := make([]int, 0)
+	var writeErr error
+	flush := func() {
+		// The tokens are recorded before their events are sent, so they can always be cleaned up.
+		if writeErr == nil && cfg.Tokens != nil {
+			writeErr = cfg.Tokens.Flush()
+		}
+		if writeErr == nil && cfg.Sink != nil && len(batch) > 0 {
+			writeErr = cfg.Sink.Write(batch)
+		}
+		batch = make([]insights.Event, 0, SinkBatchSize)
+		if writeErr == nil && cfg.Checkpoint != nil && len(sessionsDone) > 0 {
+			cfg.Checkpoint.Add(sessionsDone)
+			writeErr = cfg.Checkpoint.Save()
+		}
+		sessionsDone = sessionsDone[:0]
+		if writeErr != nil {
+			cancel()
+		}
 	}
-
-	sort.Sort(stats) // Sort by number of search events
-
-	if cfg.DryRun {
-		return stats, nil
+	for event := range events {
+		if event.EndOfSession != nil {
+			sessionsDone = append(sessionsDone, event.EndOfSession.Index)
+			continue
+		}
+		stats.Add(event)
+		if cfg.Sink == nil || event.InsightEvent == nil {
+			continue
+		}
+		if writeErr == nil && cfg.Tokens != nil {
+			writeErr = cfg.Tokens.Record(event.InsightEvent.UserToken)
+		}
+		batch = append(batch, *event.InsightEvent)
+		if len(batch) == SinkBatchSize || writeErr != nil {
+			flush()
+		}
 	}
+	flush()
 
-	// Send events to Insights API.
-	var insightsEvent []insights.Event
-	for _, event := range eventsList {
-		if event.InsightEvent != nil {
-			insightsEvent = append(insightsEvent, *event.InsightEvent)
-		}
+	if sessionErrors.Count > 0 && cfg.IO != nil {
+		fmt.Fprintf(cfg.IO.ErrOut, "Warning: %d sessions failed, last error: %v\n", sessionErrors.Count, sessionErrors.Last)
 	}
-	err := SendEvents(cfg.InsightsClient, insightsEvent)
-	if err != nil {
-		return nil, err
+	if writeErr != nil {
+		return nil, writeErr
 	}
-	return stats, nil
+	return stats.List(), ctx.Err()
 }
@@ -0,0 +1,245 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Growth models
+const (
+	GrowthLinear   = "linear"
+	GrowthLogistic = "logistic"
+	GrowthStep     = "step"
+	GrowthSeasonal = "seasonal"
+
+	// growthCalendar is the growth of a schedule calendar (see Calendar.Growth).
+	growthCalendar = "calendar"
+)
+
+const daysPerYear = 365.25
+
+// Growth is a growth curve over time: a multiplier of the number of users and of the
+// click-through and conversion rates, depending on the date. It is set globally, or per
+// search term for its rates.
+type Growth struct {
+	Model string `json:"model"`
+
+	// Origin is the start date of the linear, logistic and step growths (YYYY-MM-DD),
+	// e.g. the launch of a merchandising rule. The multiplier is 1 before.
+	Origin string `json:"origin,omitempty"`
+	// Rate is the daily increase of the multiplier of the linear growth.
+	// The parameters which can be 0 are pointers: nil is the default value.
+	Rate *float64 `json:"rate,omitempty"`
+	// Target is the final multiplier of the logistic and step growths.
+	Target *float64 `json:"target,omitempty"`
+	// Midpoint and Steepness shape the logistic growth: the number of days after the origin
+	// to get halfway to the target, and the steepness of the curve around the midpoint.
+	Midpoint  *float64 `json:"midpoint,omitempty"`
+	Steepness float64  `json:"steepness,omitempty"`
+
+	// Seasonal growth: a yearly wave of Amplitude around 1, peaking on Peak (MM-DD), and a Spike
+	// multiplier on Holiday (MM-DD), fading over SpikeDays days around it.
+	Amplitude float64  `json:"amplitude,omitempty"`
+	Peak      string   `json:"peak,omitempty"`
+	Holiday   string   `json:"holiday,omitempty"`
+	Spike     *float64 `json:"spike,omitempty"`
+	SpikeDays float64  `json:"spike_days,omitempty"`
+
+	// Caps: the maximum multiplier, and the maximum click-through and conversion rates, in percent.
+	// The rates are always capped to 100%.
+	Max                 float64 `json:"max,omitempty"`
+	MaxClickThroughRate float64 `json:"max_click_through_rate,omitempty"`
+	MaxConversionRate   float64 `json:"max_conversion_rate,omitempty"`
+
+	origin   time.Time
+	peak     time.Time
+	holiday  time.Time
+	calendar Calendar
+
+	// The parameters with their default values, set by Init.
+	rate, target, midpoint, steepness, spike, spikeDays float64
+}
+
+// ParseGrowth parses a growth flag value: the model and its parameters, e.g.
+// `logistic:origin=2026-09-01,target=1.5,max-conversion-rate=12`.
+func ParseGrowth(spec string) (*Growth, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), ":", 2)
+	params := map[string]interface{}{"model": parts[0]}
+	if len(parts) == 2 {
+		for _, item := range strings.Split(parts[1], ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid growth parameter %q: expected <name>=<value>", item)
+			}
+			name := strings.ReplaceAll(kv[0], "-", "_")
+			if value, err := strconv.ParseFloat(kv[1], 64); err == nil {
+				params[name] = value
+			} else {
+				params[name] = kv[1]
+			}
+		}
+	}
+
+	// The parameters are decoded as the JSON fields of the growth.
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	g := &Growth{}
+	if err := decoder.Decode(g); err != nil {
+		return nil, fmt.Errorf("invalid growth %q: %v", spec, err)
+	}
+	if err := g.Init(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Init checks the growth, parses its dates and resolves the default values of its parameters.
+func (g *Growth) Init() error {
+	var err error
+	switch g.Model {
+	case GrowthLinear, GrowthLogistic, GrowthStep:
+		if g.Origin == "" {
+			return fmt.Errorf("%s growth: missing origin", g.Model)
+		}
+		g.origin, err = time.ParseInLocation("2006-01-02", g.Origin, time.Local)
+		if err != nil {
+			return fmt.Errorf("%s growth: invalid origin: %v", g.Model, err)
+		}
+	case GrowthSeasonal:
+		if g.Peak == "" && g.Holiday == "" {
+			return fmt.Errorf("seasonal growth: missing peak or holiday")
+		}
+		if g.Peak != "" {
+			if g.peak, err = time.Parse("01-02", g.Peak); err != nil {
+				return fmt.Errorf("seasonal growth: invalid peak: %v", err)
+			}
+		}
+		if g.Holiday != "" {
+			if g.holiday, err = time.Parse("01-02", g.Holiday); err != nil {
+				return fmt.Errorf("seasonal growth: invalid holiday: %v", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown growth model: %q (linear, logistic, step or seasonal)", g.Model)
+	}
+
+	// The default linear growth is the former accelerator: +100% in 200 days.
+	g.rate = valueOr(g.Rate, 0.005)
+	g.target = valueOr(g.Target, 2)
+	g.midpoint = valueOr(g.Midpoint, 30)
+	g.spike = valueOr(g.Spike, 2)
+	g.steepness, g.spikeDays = g.Steepness, g.SpikeDays
+	if g.steepness == 0 {
+		g.steepness = 0.2
+	}
+	if g.spikeDays == 0 {
+		g.spikeDays = 3
+	}
+	if g.target < 0 || g.spike < 0 || g.Max < 0 || g.MaxClickThroughRate < 0 || g.MaxConversionRate < 0 {
+		return fmt.Errorf("%s growth: negative multiplier or cap", g.Model)
+	}
+	if g.steepness < 0 || g.spikeDays < 0 {
+		return fmt.Errorf("%s growth: negative steepness or spike days", g.Model)
+	}
+	return nil
+}
+
+// Multiplier returns the multiplier of the growth at a time, capped to Max if defined.
+func (g *Growth) Multiplier(t time.Time) float64 {
+	m := 1.0
+	days := t.Sub(g.origin).Hours() / 24
+	switch g.Model {
+	case GrowthLinear:
+		if days > 0 {
+			m = 1 + g.rate*days
+		}
+	case GrowthLogistic:
+		if days > 0 {
+			m = 1 + (g.target-1)/(1+math.Exp(-g.steepness*(days-g.midpoint)))
+		}
+	case GrowthStep:
+		if days >= 0 {
+			m = g.target
+		}
+	case GrowthSeasonal:
+		if g.Peak != "" {
+			m *= 1 + g.Amplitude*math.Cos(2*math.Pi*daysToYearly(t, g.peak)/daysPerYear)
+		}
+		if g.Holiday != "" {
+			d := daysToYearly(t, g.holiday) / g.spikeDays
+			m *= 1 + (g.spike-1)*math.Exp(-d*d)
+		}
+	case growthCalendar:
+		m = g.calendar.Multiplier(t)
+	}
+
+	if g.Max > 0 && m > g.Max {
+		m = g.Max
+	}
+	return math.Max(m, 0)
+}
+
+// Users returns a number of users grown at a time.
+func (g *Growth) Users(users int, t time.Time) int {
+	return int(math.Round(float64(users) * g.Multiplier(t)))
+}
+
+// ClickThroughRate returns a click-through rate (in percent) grown at a time, capped to MaxClickThroughRate.
+func (g *Growth) ClickThroughRate(rate float64, t time.Time) float64 {
+	return capRate(rate*g.Multiplier(t), g.MaxClickThroughRate)
+}
+
+// ConversionRate returns a conversion rate (in percent) grown at a time, capped to MaxConversionRate.
+func (g *Growth) ConversionRate(rate float64, t time.Time) float64 {
+	return capRate(rate*g.Multiplier(t), g.MaxConversionRate)
+}
+
+func capRate(rate float64, max float64) float64 {
+	if max > 0 && rate > max {
+		return max
+	}
+	return math.Min(rate, 100)
+}
+
+// valueOr returns the value of an optional parameter, or its default value if it is not set.
+func valueOr(value *float64, defaultValue float64) float64 {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+// daysToYearly returns the number of days between a time and the closest occurrence of a yearly date
+// (only its month and day are used), negative if the occurrence is after the time.
+func daysToYearly(t time.Time, yearly time.Time) float64 {
+	closest := math.Inf(1)
+	for year := t.Year() - 1; year <= t.Year()+1; year++ {
+		occurrence := time.Date(year, yearly.Month(), yearly.Day(), 0, 0, 0, 0, t.Location())
+		days := t.Sub(occurrence).Hours() / 24
+		if math.Abs(days) < math.Abs(closest) {
+			closest = days
+		}
+	}
+	return closest
+}
+
+// termGrowth returns the growth of a search term: its own growth, or the global one.
+func (cfg *Config) termGrowth(term *SearchTerm) *Growth {
+	if term.Growth != nil {
+		return term.Growth
+	}
+	return cfg.Growth
+}
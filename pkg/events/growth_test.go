@@ -0,0 +1,116 @@
+package events
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGrowth(t *testing.T) {
+	g, err := ParseGrowth("logistic:origin=2026-09-01,target=1.5,max-conversion-rate=12")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g.Model != GrowthLogistic || *g.Target != 1.5 || g.MaxConversionRate != 12 || g.Midpoint != nil || g.midpoint != 30 {
+		t.Errorf("unexpected growth: %+v", g)
+	}
+
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{"exponential:origin=2026-09-01", "unknown growth model"},
+		{"linear", "linear growth: missing origin"},
+		{"linear:origin=09/01/2026", "linear growth: invalid origin"},
+		{"linear:origin=2026-09-01,speed=2", `unknown field "speed"`},
+		{"linear:origin=2026-09-01,rate", "expected <name>=<value>"},
+		{"step:origin=2026-09-01,target=-1", "negative multiplier or cap"},
+		{"logistic:origin=2026-09-01,steepness=-1", "negative steepness or spike days"},
+		{"seasonal:amplitude=0.2", "missing peak or holiday"},
+		{"seasonal:holiday=11-31", "seasonal growth: invalid holiday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParseGrowth(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseGrowth(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGrowth_Multiplier(t *testing.T) {
+	origin := time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local)
+	day := func(days int) time.Time { return origin.AddDate(0, 0, days) }
+
+	tests := []struct {
+		spec string
+		time time.Time
+		want float64
+	}{
+		{"linear:origin=2026-09-01", day(-10), 1},
+		{"linear:origin=2026-09-01", day(100), 1.5},
+		{"linear:origin=2026-09-01,rate=0.01,max=1.5", day(100), 1.5},
+		// The parameters set to 0 are not replaced by their default value.
+		{"linear:origin=2026-09-01,rate=0", day(100), 1},
+		{"logistic:origin=2026-09-01,target=3,midpoint=0", day(1), 1 + 2/(1+math.Exp(-0.2))},
+		{"step:origin=2026-09-01,target=0", day(0), 0},
+		{"seasonal:holiday=11-27,spike=0", time.Date(2026, 11, 27, 0, 0, 0, 0, time.Local), 0},
+		{"logistic:origin=2026-09-01,target=3,midpoint=20", day(-1), 1},
+		{"logistic:origin=2026-09-01,target=3,midpoint=20", day(20), 2},
+		{"step:origin=2026-09-01,target=1.5", day(-1), 1},
+		{"step:origin=2026-09-01,target=1.5", day(0), 1.5},
+		{"seasonal:peak=12-15,amplitude=0.5", time.Date(2026, 12, 15, 0, 0, 0, 0, time.Local), 1.5},
+		{"seasonal:peak=12-15,amplitude=0.5", time.Date(2027, 12, 15, 0, 0, 0, 0, time.Local), 1.5},
+		{"seasonal:holiday=11-27,spike=3", time.Date(2026, 11, 27, 0, 0, 0, 0, time.Local), 3},
+		{"seasonal:holiday=11-27,spike=3,max=2.5", time.Date(2026, 11, 27, 0, 0, 0, 0, time.Local), 2.5},
+		{"seasonal:holiday=11-27,spike=3", time.Date(2026, 6, 1, 0, 0, 0, 0, time.Local), 1},
+		// The holiday is the closest one, in the previous year.
+		{"seasonal:holiday=12-31,spike=3,spike_days=1", time.Date(2027, 1, 1, 0, 0, 0, 0, time.Local), 1 + 2*math.Exp(-1)},
+	}
+	for _, tt := range tests {
+		g, err := ParseGrowth(tt.spec)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := g.Multiplier(tt.time); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("%s at %s: got %f, want %f", tt.spec, tt.time.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestSearchEvent_Growth(t *testing.T) {
+	global, err := ParseGrowth("linear:origin=2026-09-01,rate=0.1,max-click-through-rate=50")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	term, err := ParseGrowth("step:origin=2026-09-01,target=20")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &Config{ClickThroughRate: 20, ConversionRate: 10, Growth: global}
+	at := time.Date(2026, 9, 11, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		event   SearchEvent
+		wantCTR float64
+		wantCVR float64
+	}{
+		{"before the origin", SearchEvent{Time: at.AddDate(0, -1, 0)}, 0.2, 0.1},
+		{"global growth", SearchEvent{Time: at}, 0.4, 0.2},
+		{"global cap", SearchEvent{Time: at.AddDate(0, 0, 10)}, 0.5, 0.3},
+		{"term growth, capped to 100%", SearchEvent{Time: at, Term: SearchTerm{Growth: term}}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ctr := tt.event.ClickThroughRate(cfg); math.Abs(ctr-tt.wantCTR) > 1e-9 {
+				t.Errorf("ClickThroughRate() = %f, want %f", ctr, tt.wantCTR)
+			}
+			if cvr := tt.event.ConversionRate(cfg); math.Abs(cvr-tt.wantCVR) > 1e-9 {
+				t.Errorf("ConversionRate() = %f, want %f", cvr, tt.wantCVR)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// keyboardRows is the layout of a QWERTY keyboard, used to simulate the typos.
+var keyboardRows = []string{
+	"1234567890",
+	"qwertyuiop",
+	"asdfghjkl",
+	"zxcvbnm",
+}
+
+// adjacentKeys returns the keys next to a key on a QWERTY keyboard: on the same row and on the rows
+// above and below (each row being shifted to the right of the one above). It returns nil for the keys
+// not on the layout.
+func adjacentKeys(key rune) []rune {
+	key = unicode.ToLower(key)
+	for row, keys := range keyboardRows {
+		col := strings.IndexRune(keys, key)
+		if col < 0 {
+			continue
+		}
+		var adjacent []rune
+		addKeys := func(row int, from int, to int) {
+			if row < 0 || row >= len(keyboardRows) {
+				return
+			}
+			for c := from; c <= to; c++ {
+				if c >= 0 && c < len(keyboardRows[row]) {
+					adjacent = append(adjacent, rune(keyboardRows[row][c]))
+				}
+			}
+		}
+		addKeys(row-1, col, col+1)
+		addKeys(row, col-1, col-1)
+		addKeys(row, col+1, col+1)
+		addKeys(row+1, col-1, col)
+		return adjacent
+	}
+	return nil
+}
+
+// typoKey returns a key typed by mistake instead of the given one (an adjacent key),
+// or 0 if the key is not on the layout.
+func typoKey(r *rand.Rand, key rune) rune {
+	adjacent := adjacentKeys(key)
+	if len(adjacent) == 0 {
+		return 0
+	}
+	return adjacent[r.Intn(len(adjacent))]
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/errs"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+// RateLimiter is a token bucket limiting the number of queries per second.
+type RateLimiter struct {
+	mu     sync.Mutex
+	qps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing qps queries per second, with bursts of up to burst queries.
+func NewRateLimiter(qps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		qps:    qps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Reserve takes a token from the bucket and returns how long to wait before using it.
+func (l *RateLimiter) Reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.qps
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.qps * float64(time.Second))
+}
+
+// Wait blocks until a token is available or the context is canceled.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	return sleep(ctx, l.Reserve())
+}
+
+// sleep pauses for the given duration, returning early with the context error if it is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RetrySearcher is a Searcher rate limiting the searches and retrying them on transient errors,
+// with an exponential backoff. The waits are interrupted if the context passed in the search
+// options is canceled.
+type RetrySearcher struct {
+	Searcher Searcher
+	Limiter  *RateLimiter
+	Retries  int
+	Backoff  time.Duration
+}
+
+func (s *RetrySearcher) GetName() string {
+	return s.Searcher.GetName()
+}
+
+func (s *RetrySearcher) Search(query string, opts ...interface{}) (search.QueryRes, error) {
+	ctx := contextFromOpts(opts)
+	backoff := s.Backoff
+	for attempt := 0; ; attempt++ {
+		if s.Limiter != nil {
+			if err := s.Limiter.Wait(ctx); err != nil {
+				return search.QueryRes{}, err
+			}
+		}
+		res, err := s.Searcher.Search(query, opts...)
+		if err == nil || attempt >= s.Retries || !IsTransientError(err) || ctx.Err() != nil {
+			return res, err
+		}
+		// Jitter, so the retries of concurrent searches are spread.
+		if err := sleep(ctx, backoff/2+time.Duration(rand.Int63n(int64(backoff/2)+1))); err != nil {
+			return search.QueryRes{}, err
+		}
+		backoff *= 2
+	}
+}
+
+// IsTransientError returns true if a failed search is worth retrying:
+// network errors and timeouts, rate limiting and server errors.
+func IsTransientError(err error) bool {
+	if e, ok := errs.IsAlgoliaErr(err); ok {
+		return e.Status == http.StatusTooManyRequests || e.Status >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	var noMoreHostErr *errs.NoMoreHostToTryErr
+	return errors.Is(err, errs.ErrNoMoreHostToTry) || errors.As(err, &noMoreHostErr) ||
+		errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/errs"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	l := NewRateLimiter(10, 2)
+
+	// The burst is available right away.
+	for i := 0; i < 2; i++ {
+		if wait := l.Reserve(); wait != 0 {
+			t.Errorf("reservation #%d: expected no wait, got %v", i, wait)
+		}
+	}
+	// Then one token every 100ms.
+	if wait := l.Reserve(); wait < 90*time.Millisecond || wait > 100*time.Millisecond {
+		t.Errorf("expected a wait of ~100ms, got %v", wait)
+	}
+	if wait := l.Reserve(); wait < 190*time.Millisecond || wait > 200*time.Millisecond {
+		t.Errorf("expected a wait of ~200ms, got %v", wait)
+	}
+}
+
+// failingSearcher fails the first searches with the given error.
+type failingSearcher struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *failingSearcher) GetName() string { return "failing" }
+
+func (s *failingSearcher) Search(query string, opts ...interface{}) (search.QueryRes, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return search.QueryRes{}, s.err
+	}
+	return search.QueryRes{Query: query}, nil
+}
+
+func TestRetrySearcher_Search(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "no error", failures: 0, wantCalls: 1},
+		{name: "transient network error", failures: 2, err: errs.ErrNoMoreHostToTry, wantCalls: 3},
+		{name: "rate limited", failures: 1, err: errs.AlgoliaErr{Status: 429}, wantCalls: 2},
+		{name: "timeout", failures: 1, err: &net.OpError{Op: "read", Err: errors.New("i/o timeout")}, wantCalls: 2},
+		{name: "too many failures", failures: 5, err: errs.ErrNoMoreHostToTry, wantCalls: 4, wantErr: true},
+		{name: "permanent error", failures: 1, err: errs.AlgoliaErr{Status: 400}, wantCalls: 1, wantErr: true},
+		{name: "unsupported local filter", failures: 1, err: ErrUnsupportedFilter, wantCalls: 1, wantErr: true},
+		{name: "canceled", failures: 1, err: context.Canceled, wantCalls: 1, wantErr: true},
+		{name: "invalid response", failures: 1, err: errors.New("cannot decode value"), wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher := &failingSearcher{failures: tt.failures, err: tt.err}
+			s := &RetrySearcher{Searcher: searcher, Retries: 3, Backoff: time.Millisecond}
+			_, err := s.Search("jacket")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RetrySearcher.Search() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if searcher.calls != tt.wantCalls {
+				t.Errorf("RetrySearcher.Search() calls = %d, want %d", searcher.calls, tt.wantCalls)
+			}
+		})
+	}
+}
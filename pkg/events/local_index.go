@@ -0,0 +1,289 @@
+package events
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+// ErrUnsupportedFilter is returned by LocalIndex.Search for the filters it cannot handle.
+var ErrUnsupportedFilter = errors.New("unsupported filter")
+
+// LocalIndex is an offline Searcher working on a JSON records dump.
+// It only supports the subset of the search API used by the tool: the query text,
+// the `attribute:"value"` filters (combined with AND / OR) and the pagination.
+type LocalIndex struct {
+	Name    string
+	Records []map[string]interface{}
+}
+
+// NewLocalIndex loads a JSON records dump (an array of records, as exported from the dashboard).
+func NewLocalIndex(name string, fileName string) (*LocalIndex, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	bytes, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &LocalIndex{Name: name}
+	if err := json.Unmarshal(bytes, &index.Records); err != nil {
+		return nil, err
+	}
+	for i, record := range index.Records {
+		if _, ok := record["objectID"].(string); !ok {
+			return nil, fmt.Errorf("record #%d has no objectID", i)
+		}
+	}
+	return index, nil
+}
+
+func (i *LocalIndex) GetName() string {
+	return i.Name
+}
+
+// HasAttribute returns true if at least one record has a value for the (possibly nested) attribute.
+func (i *LocalIndex) HasAttribute(attribute string) bool {
+	for _, record := range i.Records {
+		if len(AttributeValues(record, attribute)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns the records matching the query and the filters, in the order of the dump.
+func (i *LocalIndex) Search(query string, opts ...interface{}) (search.QueryRes, error) {
+	if err := contextFromOpts(opts).Err(); err != nil {
+		return search.QueryRes{}, err
+	}
+
+	hitsPerPage := opt.HitsPerPage(20).Get()
+	page := 0
+	filters := ""
+	clickAnalytics := false
+	for _, o := range opts {
+		switch v := o.(type) {
+		case *opt.HitsPerPageOption:
+			hitsPerPage = v.Get()
+		case *opt.PageOption:
+			page = v.Get()
+		case *opt.FiltersOption:
+			filters = v.Get()
+		case *opt.ClickAnalyticsOption:
+			clickAnalytics = v.Get()
+		}
+	}
+
+	filter, err := parseLocalFilters(filters)
+	if err != nil {
+		return search.QueryRes{}, err
+	}
+
+	hits := make([]map[string]interface{}, 0)
+	for _, record := range i.Records {
+		if filter.match(record) && MatchQuery(record, query) {
+			hits = append(hits, record)
+		}
+	}
+
+	res := search.QueryRes{
+		Index:       i.Name,
+		Query:       query,
+		NbHits:      len(hits),
+		HitsPerPage: hitsPerPage,
+		Page:        page,
+		Hits:        make([]map[string]interface{}, 0),
+	}
+	if hitsPerPage > 0 {
+		res.NbPages = (len(hits) + hitsPerPage - 1) / hitsPerPage
+		start := page * hitsPerPage
+		if start < len(hits) {
+			end := start + hitsPerPage
+			if end > len(hits) {
+				end = len(hits)
+			}
+			res.Hits = hits[start:end]
+		}
+	}
+	if clickAnalytics {
+		res.QueryID = localQueryID(query, filters, page, opts)
+	}
+	return res, nil
+}
+
+// localQueryID returns a queryID derived from the search parameters (and user token if any),
+// so the offline runs are reproducible.
+func localQueryID(query string, filters string, page int, opts []interface{}) string {
+	userToken := ""
+	for _, o := range opts {
+		if v, ok := o.(*opt.UserTokenOption); ok {
+			userToken = v.Get()
+		}
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s|%s|%d|%s", query, filters, page, userToken))))
+}
+
+// MatchQuery returns true if the record matches the query text, as searched offline.
+func MatchQuery(record map[string]interface{}, query string) bool {
+	return matchWords(record, strings.Fields(strings.ToLower(query)))
+}
+
+// matchWords returns true if every word of the query is found in the record's values.
+func matchWords(record map[string]interface{}, words []string) bool {
+	if len(words) == 0 {
+		return true
+	}
+	text := strings.ToLower(strings.Join(stringValues(record), " "))
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// stringValues returns all the string values of a record attribute, recursively.
+func stringValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, e := range v {
+			values = append(values, stringValues(e)...)
+		}
+		return values
+	case map[string]interface{}:
+		var values []string
+		for k, e := range v {
+			if k == "objectID" {
+				continue
+			}
+			values = append(values, stringValues(e)...)
+		}
+		return values
+	case float64, bool:
+		return []string{fmt.Sprintf("%v", v)}
+	}
+	return nil
+}
+
+// AttributeValues returns the values of a (possibly nested, dot separated) attribute of a record.
+func AttributeValues(record map[string]interface{}, attribute string) []string {
+	var value interface{} = record
+	for _, key := range strings.Split(attribute, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			var values []string
+			for _, e := range v {
+				if m, ok := e.(map[string]interface{}); ok {
+					values = append(values, AttributeValues(m, key)...)
+				}
+			}
+			return values
+		default:
+			return nil
+		}
+	}
+	return stringValues(value)
+}
+
+// FacetAttribute returns the attribute of an attributesForFaceting entry, without its modifiers
+// (e.g. `searchable(brand)` or `afterDistinct(filterOnly(color))`).
+func FacetAttribute(attribute string) string {
+	for _, modifier := range []string{"afterDistinct(", "searchable(", "filterOnly("} {
+		if strings.HasPrefix(attribute, modifier) && strings.HasSuffix(attribute, ")") {
+			return FacetAttribute(attribute[len(modifier) : len(attribute)-1])
+		}
+	}
+	return attribute
+}
+
+type localFacetFilter struct {
+	Attribute string
+	Value     string
+}
+
+// localFilters is a conjunction (AND) of disjunctions (OR) of facet filters.
+type localFilters [][]localFacetFilter
+
+func parseLocalFilters(filters string) (localFilters, error) {
+	var parsed localFilters
+	if strings.TrimSpace(filters) == "" {
+		return parsed, nil
+	}
+	if strings.Count(filters, `"`)%2 != 0 {
+		return nil, fmt.Errorf("%w: unterminated quote in %s", ErrUnsupportedFilter, filters)
+	}
+	for _, group := range splitOutsideQuotes(filters, " AND ") {
+		group = strings.TrimSpace(group)
+		group = strings.TrimSuffix(strings.TrimPrefix(group, "("), ")")
+		var or []localFacetFilter
+		for _, filter := range splitOutsideQuotes(group, " OR ") {
+			parts := strings.SplitN(strings.TrimSpace(filter), ":", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("%w: %s", ErrUnsupportedFilter, filter)
+			}
+			or = append(or, localFacetFilter{
+				Attribute: strings.Trim(parts[0], "\""),
+				Value:     strings.Trim(parts[1], "\""),
+			})
+		}
+		parsed = append(parsed, or)
+	}
+	return parsed, nil
+}
+
+// splitOutsideQuotes splits a filter around the separators outside of the quoted values,
+// e.g. brand:"Black AND White" is not split around AND.
+func splitOutsideQuotes(s string, sep string) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func (f localFilters) match(record map[string]interface{}) bool {
+	for _, or := range f {
+		matched := false
+		for _, filter := range or {
+			for _, value := range AttributeValues(record, filter.Attribute) {
+				if strings.EqualFold(value, filter.Value) {
+					matched = true
+					break
+				}
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+)
+
+func TestLocalIndex_Search(t *testing.T) {
+	index, err := NewLocalIndex("products", "testdata/records.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		opts          []interface{}
+		wantObjectIDs []string
+		wantNbHits    int
+		wantErr       bool
+	}{
+		{
+			name:          "empty query",
+			query:         "",
+			wantObjectIDs: []string{"1", "2", "3", "4"},
+			wantNbHits:    4,
+		},
+		{
+			name:          "query words",
+			query:         "Black Jacket",
+			wantObjectIDs: []string{"1"},
+			wantNbHits:    1,
+		},
+		{
+			name:          "hierarchical filter",
+			query:         "jacket",
+			opts:          []interface{}{opt.Filters(`category_page_id:"Women > Clothing > Jackets"`)},
+			wantObjectIDs: []string{"2"},
+			wantNbHits:    1,
+		},
+		{
+			name:          "nested attribute filters",
+			query:         "",
+			opts:          []interface{}{opt.Filters(`color.original_name:"black" AND brand:"Michael Kors"`)},
+			wantObjectIDs: []string{"1", "3"},
+			wantNbHits:    2,
+		},
+		{
+			name:          "disjunctive filters",
+			query:         "",
+			opts:          []interface{}{opt.Filters(`(available_sizes:"S" OR available_sizes:"43") AND gender:"women"`)},
+			wantObjectIDs: []string{"2", "3"},
+			wantNbHits:    2,
+		},
+		{
+			name:          "operators in quoted values",
+			query:         "",
+			opts:          []interface{}{opt.Filters(`brand:"Black AND White" OR brand:"Adidas"`)},
+			wantObjectIDs: []string{"4"},
+			wantNbHits:    1,
+		},
+		{
+			name:          "pagination",
+			query:         "",
+			opts:          []interface{}{opt.HitsPerPage(3), opt.Page(1)},
+			wantObjectIDs: []string{"4"},
+			wantNbHits:    4,
+		},
+		{
+			name:    "malformed filter",
+			query:   "",
+			opts:    []interface{}{opt.Filters(`brand`)},
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			query:   "",
+			opts:    []interface{}{opt.Filters(`brand:"Adidas`)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := index.Search(tt.query, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LocalIndex.Search() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			objectIDs := make([]string, 0)
+			for _, hit := range res.Hits {
+				objectIDs = append(objectIDs, hit["objectID"].(string))
+			}
+			if !reflect.DeepEqual(objectIDs, tt.wantObjectIDs) {
+				t.Errorf("LocalIndex.Search() objectIDs = %v, want %v", objectIDs, tt.wantObjectIDs)
+			}
+			if res.NbHits != tt.wantNbHits {
+				t.Errorf("LocalIndex.Search() nbHits = %v, want %v", res.NbHits, tt.wantNbHits)
+			}
+		})
+	}
+}
+
+func TestLocalIndex_QueryID(t *testing.T) {
+	index, err := NewLocalIndex("products", "testdata/records.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, _ := index.Search("dress")
+	if res.QueryID != "" {
+		t.Errorf("expected no queryID without clickAnalytics, got %q", res.QueryID)
+	}
+	res, _ = index.Search("dress", opt.ClickAnalytics(true))
+	if len(res.QueryID) != 32 {
+		t.Errorf("expected a 32 chars queryID with clickAnalytics, got %q", res.QueryID)
+	}
+}
+
+func TestFacetAttribute(t *testing.T) {
+	tests := []struct {
+		attribute string
+		want      string
+	}{
+		{attribute: "brand", want: "brand"},
+		{attribute: "searchable(brand)", want: "brand"},
+		{attribute: "filterOnly(color.original_name)", want: "color.original_name"},
+		{attribute: "afterDistinct(searchable(category_page_id))", want: "category_page_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.attribute, func(t *testing.T) {
+			if got := FacetAttribute(tt.attribute); got != tt.want {
+				t.Errorf("FacetAttribute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
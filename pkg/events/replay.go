@@ -0,0 +1,146 @@
+package events
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+// ReadEventsFile reads an events archive written by the ndjson or csv sinks.
+// The format is guessed from the file extension (`.csv` or NDJSON otherwise).
+func ReadEventsFile(fileName string) ([]insights.Event, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(fileName)) == ".csv" {
+		return ReadEventsCSV(file)
+	}
+	return ReadEventsNDJSON(file)
+}
+
+// ReadEventsNDJSON reads events in the Insights API format, one per line.
+func ReadEventsNDJSON(r io.Reader) ([]insights.Event, error) {
+	events := make([]insights.Event, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		// The timestamp is not decoded by insights.Event (milliseconds since epoch).
+		var event struct {
+			insights.Event
+			Timestamp int64 `json:"timestamp"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		event.Event.Timestamp = time.Unix(0, event.Timestamp*int64(time.Millisecond))
+		events = append(events, event.Event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReadEventsCSV reads events with the same columns as the CSV sink.
+// The index name is not part of the CSV format and must be set afterwards.
+func ReadEventsCSV(r io.Reader) ([]insights.Event, error) {
+	csvReader := csv.NewReader(r)
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range CSVHeader {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column: %s", name)
+		}
+	}
+
+	events := make([]insights.Event, 0)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		timestamp, err := time.Parse("2006-01-02T15:04:05Z", record[columns["timestamp"]])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, insights.Event{
+			UserToken: record[columns["userToken"]],
+			Timestamp: timestamp,
+			ObjectIDs: []string{record[columns["objectID"]]},
+			EventType: record[columns["eventType"]],
+			EventName: record[columns["eventName"]],
+		})
+	}
+	return events, nil
+}
+
+// Retime shifts the events timestamps so the most recent event happens at `now`.
+// The relative spacing between the events is kept.
+func Retime(events []insights.Event, now time.Time) {
+	if len(events) == 0 {
+		return
+	}
+	latest := events[0].Timestamp
+	for _, event := range events {
+		if event.Timestamp.After(latest) {
+			latest = event.Timestamp
+		}
+	}
+	offset := now.Sub(latest)
+	for i := range events {
+		events[i].Timestamp = events[i].Timestamp.Add(offset)
+	}
+}
+
+// RemapIndex sends all the events to the given index. The query IDs and positions of the events
+// of another index refer to its searches: they are removed, so the clicks and conversions after
+// a search become regular clicks and conversions.
+func RemapIndex(events []insights.Event, indexName string) {
+	for i := range events {
+		if events[i].Index != "" && events[i].Index != indexName {
+			events[i].QueryID = ""
+			events[i].Positions = nil
+		}
+		events[i].Index = indexName
+	}
+}
+
+// RemapUserTokens replaces each user token by a new random one (consistently across events),
+// prefixed with the given prefix.
+func RemapUserTokens(r *rand.Rand, events []insights.Event, prefix string) {
+	tokens := make(map[string]string)
+	for i := range events {
+		token, ok := tokens[events[i].UserToken]
+		if !ok {
+			token = fmt.Sprintf("%s%d", prefix, r.Int63())
+			tokens[events[i].UserToken] = token
+		}
+		events[i].UserToken = token
+	}
+}
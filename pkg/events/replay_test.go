@@ -0,0 +1,96 @@
+package events
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+func TestReadEventsNDJSON(t *testing.T) {
+	timestamp := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	written := []insights.Event{{
+		EventType: insights.EventTypeClick,
+		EventName: "PLP: Open product details",
+		Index:     "products",
+		UserToken: "mrs-grim",
+		Timestamp: timestamp,
+		ObjectIDs: []string{"3"},
+		Positions: []int{1},
+		QueryID:   "43b15df305339e827f0ac0bdc5ebcaa7",
+	}}
+
+	buf := bytes.Buffer{}
+	if err := NewNDJSONSink(&buf).Write(written); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	read, err := ReadEventsNDJSON(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(read) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(read))
+	}
+	if !read[0].Timestamp.Equal(timestamp) {
+		t.Errorf("expected timestamp %v, got %v", timestamp, read[0].Timestamp)
+	}
+	if read[0].QueryID != written[0].QueryID || read[0].Positions[0] != 1 || read[0].Index != "products" {
+		t.Errorf("expected %+v, got %+v", written[0], read[0])
+	}
+}
+
+func TestRetime(t *testing.T) {
+	origin := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	events := []insights.Event{
+		{Timestamp: origin},
+		{Timestamp: origin.Add(2 * time.Hour)},
+		{Timestamp: origin.Add(time.Minute)},
+	}
+
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	Retime(events, now)
+
+	expected := []time.Time{now.Add(-2 * time.Hour), now, now.Add(-2*time.Hour + time.Minute)}
+	for i, event := range events {
+		if !event.Timestamp.Equal(expected[i]) {
+			t.Errorf("event #%d: expected timestamp %v, got %v", i, expected[i], event.Timestamp)
+		}
+	}
+}
+
+func TestRemapIndex(t *testing.T) {
+	events := []insights.Event{
+		{Index: "products", QueryID: "q1", Positions: []int{3}},
+		{Index: "demo", QueryID: "q2", Positions: []int{1}},
+		{QueryID: "q3", Positions: []int{2}},
+	}
+	RemapIndex(events, "demo")
+
+	expected := []insights.Event{
+		{Index: "demo"},
+		{Index: "demo", QueryID: "q2", Positions: []int{1}},
+		{Index: "demo", QueryID: "q3", Positions: []int{2}},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("RemapIndex() = %+v, want %+v", events, expected)
+	}
+}
+
+func TestRemapUserTokens(t *testing.T) {
+	events := []insights.Event{{UserToken: "a"}, {UserToken: "b"}, {UserToken: "a"}}
+	RemapUserTokens(rand.New(rand.NewSource(1)), events, "replay-")
+
+	if events[0].UserToken != events[2].UserToken {
+		t.Errorf("expected the same user token for the same user, got %s and %s", events[0].UserToken, events[2].UserToken)
+	}
+	if events[0].UserToken == events[1].UserToken {
+		t.Errorf("expected different user tokens for different users")
+	}
+	if events[0].UserToken == "a" || events[0].UserToken[:7] != "replay-" {
+		t.Errorf("expected a new prefixed user token, got %s", events[0].UserToken)
+	}
+}
@@ -0,0 +1,184 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// memorySink keeps the events in memory.
+type memorySink struct {
+	events []insights.Event
+}
+
+func (s *memorySink) Write(events []insights.Event) error {
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+// newTestConfig returns an offline config, generating events from the testdata files.
+func newTestConfig(t *testing.T, seed int64) (*Config, *memorySink) {
+	index, err := NewLocalIndex("products", "testdata/records.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	searchTerms, err := NewSearchTerms("testdata/searches.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tags, err := NewTags(map[string]int{"desktop": 2, "mobile": 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sink := &memorySink{}
+	cfg := &Config{
+		Rand:             rand.New(rand.NewSource(seed)),
+		SearchIndex:      index,
+		Sink:             sink,
+		SearchTerms:      searchTerms,
+		TagsCollection:   []TagsCollection{{Name: "platform", Tags: tags}},
+		NumberOfUsers:    50,
+		SearchesPerUser:  3,
+		HitsPerPage:      3,
+		ClickPosition:    1,
+		ClickThroughRate: 40,
+		ConversionRate:   10,
+		RefinementRate:   20,
+		PaginationRate:   20,
+		ViewRate:         50,
+		EventsNames: EventNames{
+			"click":      {"PLP: Open product details": 2, "PLP: Add to wish list": 1},
+			"conversion": {"PLP: Add to cart": 1},
+			"view":       {"PLP: Product Viewed": 1},
+		},
+	}
+	return cfg, sink
+}
+
+// eventsNDJSON returns the events as sorted NDJSON, without the timestamps (which depend on the run time).
+func eventsNDJSON(t *testing.T, events []insights.Event) []byte {
+	buf := bytes.Buffer{}
+	sink := NewNDJSONSink(&buf)
+	for _, event := range events {
+		event.Timestamp = time.Time{}
+		if err := sink.Write([]insights.Event{event}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	lines := bytes.SplitAfter(buf.Bytes(), []byte("\n"))
+	sort.Slice(lines, func(i, j int) bool { return bytes.Compare(lines[i], lines[j]) < 0 })
+	return bytes.Join(lines, nil)
+}
+
+func TestRun_Golden(t *testing.T) {
+	cfg, sink := newTestConfig(t, 42)
+	if _, err := Run(context.Background(), cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := eventsNDJSON(t, sink.events)
+
+	golden := "testdata/run.golden"
+	if *update {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("events differ from %s (run with -update to regenerate):\n%s", golden, got)
+	}
+}
+
+// batchesSink records the size of the batches written.
+type batchesSink struct {
+	sizes []int
+}
+
+func (s *batchesSink) Write(events []insights.Event) error {
+	s.sizes = append(s.sizes, len(events))
+	return nil
+}
+
+func (s *batchesSink) Close() error { return nil }
+
+func TestRun_Batches(t *testing.T) {
+	cfg, _ := newTestConfig(t, 1)
+	sink := &batchesSink{}
+	cfg.Sink = sink
+	cfg.NumberOfUsers = 1000
+	cfg.ViewRate = 100
+
+	stats, err := Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	total := 0
+	for i, size := range sink.sizes {
+		total += size
+		if size != SinkBatchSize && i != len(sink.sizes)-1 {
+			t.Errorf("batch #%d: expected %d events, got %d", i, SinkBatchSize, size)
+		}
+	}
+	if len(sink.sizes) < 2 {
+		t.Errorf("expected several batches, got %d", len(sink.sizes))
+	}
+
+	all := stats[0].Stats
+	if expected := all.TotalClicks() + all.TotalConversions() + all.TotalViews(); total != expected {
+		t.Errorf("expected %d events written, got %d", expected, total)
+	}
+}
+
+// failingSink fails to write the events.
+type failingSink struct{}
+
+func (s *failingSink) Write(events []insights.Event) error { return errors.New("write failed") }
+
+func (s *failingSink) Close() error { return nil }
+
+// countingSearcher counts the searches.
+type countingSearcher struct {
+	Searcher
+	searches int64
+}
+
+func (s *countingSearcher) Search(query string, opts ...interface{}) (search.QueryRes, error) {
+	atomic.AddInt64(&s.searches, 1)
+	return s.Searcher.Search(query, opts...)
+}
+
+func TestRun_WriteError(t *testing.T) {
+	cfg, _ := newTestConfig(t, 1)
+	cfg.Sink = &failingSink{}
+	cfg.NumberOfUsers = 1000
+	searcher := &countingSearcher{Searcher: cfg.SearchIndex}
+	cfg.SearchIndex = searcher
+
+	_, err := Run(context.Background(), cfg)
+	if err == nil || err.Error() != "write failed" {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+	// The generation stops after the first batch, instead of running the searches of all the users.
+	if searches := atomic.LoadInt64(&searcher.searches); searches >= int64(cfg.NumberOfUsers*cfg.SearchesPerUser/2) {
+		t.Errorf("expected the generation to stop on the write error, got %d searches", searches)
+	}
+}
@@ -0,0 +1,171 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// hourlyTraffic is the relative amount of traffic for each hour of the day: low at night,
+// a lunch break bump and an evening peak.
+var hourlyTraffic = [24]float64{
+	0.35, 0.2, 0.12, 0.08, 0.08, 0.12, 0.3, 0.6, 0.85, 1, 1.05, 1.15,
+	1.3, 1.2, 1.05, 1, 1.05, 1.2, 1.45, 1.7, 1.85, 1.7, 1.2, 0.7,
+}
+
+// Schedule shapes the traffic of a long-lived run over time: a daily and a weekly shape,
+// and a growth calendar.
+type Schedule struct {
+	// Hours and Weekdays are the relative amounts of traffic of each hour of the day
+	// and of each day of the week (Sunday first).
+	Hours    [24]float64
+	Weekdays [7]float64
+	// Location is the time zone of the hours and the days.
+	Location *time.Location
+	Calendar Calendar
+}
+
+// Calendar is the growth of the traffic over time: the multiplier of the number of users and
+// of the click-through and conversion rates, at some dates.
+type Calendar []CalendarDate
+
+// CalendarDate is the growth multiplier at a date.
+type CalendarDate struct {
+	Date       time.Time
+	Multiplier float64
+}
+
+// scheduleFile is the JSON representation of a Schedule.
+// The missing fields keep their default value.
+type scheduleFile struct {
+	Timezone string    `json:"timezone"`
+	Hours    []float64 `json:"hours"`
+	Weekdays []float64 `json:"weekdays"`
+	Calendar []struct {
+		Date       string  `json:"date"`
+		Multiplier float64 `json:"multiplier"`
+	} `json:"calendar"`
+}
+
+// NewSchedule returns the default schedule: more traffic in the evenings, less at night,
+// peaks at the weekends, and no growth.
+func NewSchedule() *Schedule {
+	return &Schedule{
+		Hours:    hourlyTraffic,
+		Weekdays: weekdayTraffic,
+		Location: time.Local,
+	}
+}
+
+// LoadSchedule reads a schedule file.
+func LoadSchedule(fileName string) (*Schedule, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var f scheduleFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	s := NewSchedule()
+	if err := f.apply(s); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return s, nil
+}
+
+func (f *scheduleFile) apply(s *Schedule) error {
+	if f.Timezone != "" {
+		location, err := time.LoadLocation(f.Timezone)
+		if err != nil {
+			return err
+		}
+		s.Location = location
+	}
+	if f.Hours != nil {
+		if len(f.Hours) != len(s.Hours) {
+			return fmt.Errorf("hours: %d values instead of %d", len(f.Hours), len(s.Hours))
+		}
+		copy(s.Hours[:], f.Hours)
+	}
+	if f.Weekdays != nil {
+		if len(f.Weekdays) != len(s.Weekdays) {
+			return fmt.Errorf("weekdays: %d values instead of %d", len(f.Weekdays), len(s.Weekdays))
+		}
+		copy(s.Weekdays[:], f.Weekdays)
+	}
+	if mean(s.Hours[:]) <= 0 || mean(s.Weekdays[:]) <= 0 {
+		return fmt.Errorf("the hours and weekdays traffic must be positive")
+	}
+
+	for _, d := range f.Calendar {
+		date, err := time.ParseInLocation("2006-01-02", d.Date, s.Location)
+		if err != nil {
+			return fmt.Errorf("calendar: %v", err)
+		}
+		if d.Multiplier < 0 {
+			return fmt.Errorf("calendar: negative multiplier on %s", d.Date)
+		}
+		s.Calendar = append(s.Calendar, CalendarDate{Date: date, Multiplier: d.Multiplier})
+	}
+	sort.Slice(s.Calendar, func(i, j int) bool {
+		return s.Calendar[i].Date.Before(s.Calendar[j].Date)
+	})
+	return nil
+}
+
+// Traffic returns the relative amount of traffic at a time, from the hours and weekdays shapes.
+// It is 1 on average over a week.
+func (s *Schedule) Traffic(t time.Time) float64 {
+	t = t.In(s.Location)
+	return s.Hours[t.Hour()] / mean(s.Hours[:]) * s.Weekdays[t.Weekday()] / mean(s.Weekdays[:])
+}
+
+// Growth returns the growth multiplier of the calendar at a time (see Calendar.Multiplier).
+func (s *Schedule) Growth(t time.Time) float64 {
+	return s.Calendar.Multiplier(t)
+}
+
+// Growth returns the calendar as a growth of the click-through and conversion rates, nil without a calendar.
+func (c Calendar) Growth() *Growth {
+	if len(c) == 0 {
+		return nil
+	}
+	return &Growth{Model: growthCalendar, calendar: c}
+}
+
+// Multiplier returns the growth multiplier at a time, interpolated between the calendar dates.
+// It is the multiplier of the closest date before the first date or after the last one,
+// and 1 without a calendar.
+func (c Calendar) Multiplier(t time.Time) float64 {
+	if len(c) == 0 {
+		return 1
+	}
+	if !t.After(c[0].Date) {
+		return c[0].Multiplier
+	}
+	for i := 1; i < len(c); i++ {
+		if t.Before(c[i].Date) {
+			progress := float64(t.Sub(c[i-1].Date)) / float64(c[i].Date.Sub(c[i-1].Date))
+			return c[i-1].Multiplier + progress*(c[i].Multiplier-c[i-1].Multiplier)
+		}
+	}
+	return c[len(c)-1].Multiplier
+}
+
+// Users returns the expected number of users of the period starting at a time,
+// for a number of users per day.
+func (s *Schedule) Users(usersPerDay int, t time.Time, period time.Duration) float64 {
+	return float64(usersPerDay) * float64(period) / float64(day) * s.Traffic(t) * s.Growth(t)
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
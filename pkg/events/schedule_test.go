@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Traffic(t *testing.T) {
+	s := NewSchedule()
+	s.Location = time.UTC
+
+	// Over a week, the traffic is 1 on average.
+	start := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	sum := 0.0
+	for h := 0; h < 7*24; h++ {
+		sum += s.Traffic(start.Add(time.Duration(h) * time.Hour))
+	}
+	if mean := sum / (7 * 24); math.Abs(mean-1) > 1e-9 {
+		t.Errorf("expected an average traffic of 1, got %f", mean)
+	}
+
+	night := s.Traffic(time.Date(2026, 10, 14, 4, 0, 0, 0, time.UTC))
+	evening := s.Traffic(time.Date(2026, 10, 14, 20, 0, 0, 0, time.UTC))
+	saturdayEvening := s.Traffic(time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC))
+	if !(night < evening && evening < saturdayEvening) {
+		t.Errorf("expected night < evening < saturday evening, got %f, %f, %f", night, evening, saturdayEvening)
+	}
+}
+
+func TestLoadSchedule(t *testing.T) {
+	s, err := LoadSchedule("testdata/schedule.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Location.String() != "UTC" {
+		t.Errorf("expected the UTC time zone, got %s", s.Location)
+	}
+	if s.Weekdays != weekdayTraffic {
+		t.Errorf("expected the default weekdays traffic, got %v", s.Weekdays)
+	}
+
+	// Afternoons have 3 times the traffic of mornings.
+	wednesday := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	morning, afternoon := s.Traffic(wednesday.Add(9*time.Hour)), s.Traffic(wednesday.Add(15*time.Hour))
+	if math.Abs(afternoon/morning-3) > 1e-9 {
+		t.Errorf("expected 3 times the morning traffic in the afternoon, got %f and %f", morning, afternoon)
+	}
+
+	tests := []struct {
+		date     time.Time
+		expected float64
+	}{
+		{time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), 1},
+		{time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), 1},
+		{time.Date(2026, 1, 30, 12, 0, 0, 0, time.UTC), 1.5},
+		{time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), 2},
+		{time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), 2},
+	}
+	for _, tt := range tests {
+		if growth := s.Growth(tt.date); math.Abs(growth-tt.expected) > 1e-9 {
+			t.Errorf("%s: expected a growth of %f, got %f", tt.date.Format("2006-01-02"), tt.expected, growth)
+		}
+	}
+
+	// The calendar grows the rates, capped to 100%.
+	growth := s.Calendar.Growth()
+	june := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	if ctr, cvr := growth.ClickThroughRate(40, june), growth.ConversionRate(60, june); ctr != 80 || cvr != 100 {
+		t.Errorf("expected a CTR of 80%% and a CVR of 100%%, got %f and %f", ctr, cvr)
+	}
+}
+
+func TestLoadSchedule_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"hours", `{"hours": [1, 2, 3]}`, "hours: 3 values instead of 24"},
+		{"weekdays", `{"weekdays": [0, 0, 0, 0, 0, 0, 0]}`, "must be positive"},
+		{"timezone", `{"timezone": "Mars/Olympus"}`, "unknown time zone"},
+		{"date", `{"calendar": [{"date": "2026-13-01", "multiplier": 1}]}`, "calendar: parsing time"},
+		{"multiplier", `{"calendar": [{"date": "2026-01-01", "multiplier": -1}]}`, "negative multiplier on 2026-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "schedule.json")
+			if err := ioutil.WriteFile(fileName, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err := LoadSchedule(fileName)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	cfg, sink := newTestConfig(t, 1)
+	// 10 users and 2 personas per period of an hour, with a flat traffic shape and a growth of 2.
+	cfg.NumberOfUsers = 240
+	for i := 0; i < 48; i++ {
+		cfg.PersonaUsers = append(cfg.PersonaUsers, &User{Token: fmt.Sprintf("persona-%d", i)})
+	}
+	s := NewSchedule()
+	for i := range s.Hours {
+		s.Hours[i] = 1
+	}
+	for i := range s.Weekdays {
+		s.Weekdays[i] = 1
+	}
+	s.Calendar = Calendar{{Date: time.Now().Add(-time.Hour), Multiplier: 2}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var ticks []Tick
+	err := Serve(ctx, cfg, s, time.Hour, func(tick Tick) {
+		ticks = append(ticks, tick)
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ticks) != 1 {
+		t.Fatalf("expected 1 tick, got %d", len(ticks))
+	}
+	tick := ticks[0]
+	if tick.Err != nil {
+		t.Fatalf("unexpected error: %v", tick.Err)
+	}
+	if tick.Users != 24 || tick.Growth != 2 {
+		t.Errorf("expected 24 users with a growth of 2, got %d users with a growth of %f", tick.Users, tick.Growth)
+	}
+	if searches := tick.Stats[0].TotalSearches; searches != 24*cfg.SearchesPerUser {
+		t.Errorf("expected %d searches, got %d", 24*cfg.SearchesPerUser, searches)
+	}
+	personas := map[string]bool{}
+	for _, e := range sink.events {
+		if strings.HasPrefix(e.UserToken, "persona-") {
+			personas[e.UserToken] = true
+		}
+	}
+	if len(personas) != 4 {
+		t.Errorf("expected 4 personas, got %v", personas)
+	}
+	if len(sink.events) == 0 {
+		t.Errorf("expected events")
+	}
+	// The rates of the config are left untouched.
+	if cfg.NumberOfUsers != 240 || cfg.ClickThroughRate != 40 {
+		t.Errorf("expected the config to be left untouched, got %d users and %f CTR", cfg.NumberOfUsers, cfg.ClickThroughRate)
+	}
+}
@@ -0,0 +1,25 @@
+package events
+
+import (
+	"context"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+// Searcher is the search backend used to generate the search traffic.
+// It is implemented by `*search.Index` and by `*LocalIndex` (offline mode).
+// As for the Algolia client, a context.Context can be given in the search options.
+type Searcher interface {
+	GetName() string
+	Search(query string, opts ...interface{}) (search.QueryRes, error)
+}
+
+// contextFromOpts returns the context given in the search options, if any.
+func contextFromOpts(opts []interface{}) context.Context {
+	for _, o := range opts {
+		if ctx, ok := o.(context.Context); ok {
+			return ctx
+		}
+	}
+	return context.Background()
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"sort"
 	"strings"
 
 	wr "github.com/mroth/weightedrand"
@@ -43,35 +44,58 @@ func (t *SearchTerms) NewChooser() error {
 	return nil
 }
 
-func (t *SearchTerms) Pick() SearchTerm {
-	return t.Chooser.Pick().(SearchTerm)
+func (t *SearchTerms) Pick(r *rand.Rand) SearchTerm {
+	return t.Chooser.PickSource(r).(SearchTerm)
 }
 
 type Filters map[string]map[string]int
 
-func (f Filters) Pick() (string, error) {
+func (f Filters) Pick(r *rand.Rand) (string, error) {
 	if len(f) == 0 {
 		return "", nil
 	}
 
 	filters := make([]string, 0)
-	for filterName, values := range f {
+	for _, filterName := range sortedGroupKeys(f) {
+		values := f[filterName]
 		choices := make([]wr.Choice, 0)
-		for k, w := range values {
+		for _, k := range sortedWeightKeys(values) {
 			choices = append(choices, wr.Choice{
 				Item:   fmt.Sprintf("%s:\"%s\"", filterName, k),
-				Weight: uint(w),
+				Weight: uint(values[k]),
 			})
 		}
 		chooser, err := wr.NewChooser(choices...)
 		if err != nil {
 			return "", err
 		}
-		filters = append(filters, chooser.Pick().(string))
+		filters = append(filters, chooser.PickSource(r).(string))
 	}
 	return strings.Join(filters, " AND "), nil
 }
 
+// sortedWeightKeys returns the values of a weighted choice in a stable order, so the random choices
+// are reproducible.
+func sortedWeightKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedGroupKeys returns the names of groups of weighted choices (filters, tags collections or
+// event types) in a stable order, so the random choices are reproducible.
+func sortedGroupKeys(m map[string]map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 type SearchTerm struct {
 	Term             string   `json:"term"`
 	ClickThroughRate float64  `json:"click_through_rate,omitempty"`
@@ -80,13 +104,19 @@ type SearchTerm struct {
 	Synonyms         []string `json:"synonyms,omitempty"`
 	Filters          Filters  `json:"filters,omitempty"`
 	NoResults        bool     `json:"no_results,omitempty"`
+
+	// Variations of the query, instead of the global ones.
+	Variations *QueryVariations `json:"variations,omitempty"`
+
+	// Growth of the click-through and conversion rates of the term, instead of the global one.
+	Growth *Growth `json:"growth,omitempty"`
 }
 
-func (t *SearchTerm) PickSynonym() string {
+func (t *SearchTerm) PickSynonym(r *rand.Rand) string {
 	if len(t.Synonyms) == 0 {
 		return ""
 	}
-	return t.Synonyms[rand.Intn(len(t.Synonyms))]
+	return t.Synonyms[r.Intn(len(t.Synonyms))]
 }
 
 func NewSearchTerms(fileName string) (*SearchTerms, error) {
@@ -98,11 +128,24 @@ func NewSearchTerms(fileName string) (*SearchTerms, error) {
 
 	bytes, _ := ioutil.ReadAll(file)
 
-	searchTerms := &SearchTerms{}
-	if err := json.Unmarshal(bytes, &searchTerms.SearchTerms); err != nil {
+	var terms []SearchTerm
+	if err := json.Unmarshal(bytes, &terms); err != nil {
 		return nil, err
 	}
+	return NewSearchTermsFromList(terms)
+}
 
+// NewSearchTermsFromList returns the search terms, picked in the order of the list.
+func NewSearchTermsFromList(terms []SearchTerm) (*SearchTerms, error) {
+	for _, term := range terms {
+		if term.Growth == nil {
+			continue
+		}
+		if err := term.Growth.Init(); err != nil {
+			return nil, fmt.Errorf("search term %q: %v", term.Term, err)
+		}
+	}
+	searchTerms := &SearchTerms{SearchTerms: terms}
 	if err := searchTerms.NewChooser(); err != nil {
 		return nil, err
 	}
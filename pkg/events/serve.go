@@ -0,0 +1,78 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// Tick is the outcome of a period of a Serve run.
+type Tick struct {
+	Time time.Time
+	// Users is the number of users of the period, personas included.
+	Users  int
+	Growth float64
+	Stats  StatsPerTermList
+	// Err is the error of the events generation of the period.
+	Err error
+}
+
+// Serve generates events until the context is canceled, with a run at the start of every period.
+// The number of users of a run follows the schedule, cfg.NumberOfUsers being the number of users
+// per day, and the growth calendar of the schedule is the growth of the click-through and conversion rates
+// (cfg.Growth is replaced).
+// The personas follow the schedule as well, each of them running about once a day: the personas of a run
+// are sampled among cfg.PersonaUsers.
+// The fractions of users are carried over to the next periods, so the quiet hours still get some traffic.
+// The errors of a run don't stop the serve: they are reported with the tick.
+func Serve(ctx context.Context, cfg *Config, schedule *Schedule, period time.Duration, report func(Tick)) error {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	growth := schedule.Calendar.Growth()
+	carry, personasCarry := 0.0, 0.0
+	for {
+		now := time.Now()
+		carry += schedule.Users(cfg.NumberOfUsers, now, period)
+		users := int(carry)
+		carry -= float64(users)
+		personasCarry += schedule.Users(len(cfg.PersonaUsers), now, period)
+		personas := samplePersonas(cfg, int(personasCarry))
+		personasCarry -= math.Floor(personasCarry)
+		tick := Tick{Time: now, Users: users + len(personas), Growth: schedule.Growth(now)}
+
+		if tick.Users > 0 {
+			run := *cfg
+			run.NumberOfUsers = users
+			run.PersonaUsers = personas
+			run.Growth = growth
+			tick.Stats, tick.Err = Run(ctx, &run)
+			if errors.Is(tick.Err, context.Canceled) {
+				tick.Err = nil
+			}
+		}
+		report(tick)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// samplePersonas returns n personas of cfg.PersonaUsers drawn without replacement, at most all of them.
+func samplePersonas(cfg *Config, n int) []*User {
+	if n <= 0 {
+		return nil
+	}
+	if n >= len(cfg.PersonaUsers) {
+		return cfg.PersonaUsers
+	}
+	personas := make([]*User, n)
+	for i, j := range cfg.Rand.Perm(len(cfg.PersonaUsers))[:n] {
+		personas[i] = cfg.PersonaUsers[j]
+	}
+	return personas
+}
@@ -0,0 +1,191 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+const (
+	// sameObjectConversionRate is the probability for a conversion to happen on the clicked object,
+	// instead of another object of the same results.
+	sameObjectConversionRate = 0.9
+)
+
+// Session is a search session of a user: a search, eventually followed by a refinement
+// or a pagination search, a click on one of the hits and a conversion after the click.
+type Session struct {
+	User  *User
+	Cfg   *Config
+	Start time.Time
+}
+
+// after returns a time between min and max after t.
+func (s *Session) after(t time.Time, min time.Duration, max time.Duration) time.Time {
+	return t.Add(min + time.Duration(s.User.Rand.Int63n(int64(max-min))))
+}
+
+// Run generates the events of the session.
+// The events generated before an error are returned along with the error.
+func (s *Session) Run(ctx context.Context) ([]Event, error) {
+	cfg, user, r := s.Cfg, s.User, s.User.Rand
+	events := make([]Event, 0)
+	// Without view events names (e.g. an older events names file), no view events are sent.
+	views := r.Float64() < cfg.ViewRate/100 && len(cfg.EventsNames[insights.EventTypeView]) > 0
+
+	// addSearch adds a search event, and the view events of its hits if the user sends views.
+	addSearch := func(searchEvent *SearchEvent, eventTime time.Time) error {
+		searchEvent.Time = eventTime
+		events = append(events, Event{SearchEvent: searchEvent})
+		if !views {
+			return nil
+		}
+		viewEvents, err := NewViewEvents(user, cfg, s.after(eventTime, time.Second, 3*time.Second), searchEvent)
+		if err != nil {
+			return err
+		}
+		events = append(events, viewEvents...)
+		return nil
+	}
+
+	searchEvent, err := user.Search(ctx, cfg)
+	if err != nil {
+		return events, err
+	}
+	eventTime := s.Start
+	if err := addSearch(searchEvent, eventTime); err != nil {
+		return events, err
+	}
+
+	// Refinement: the user searches again with other filters values.
+	filters := user.Filters
+	if len(filters) == 0 {
+		filters = searchEvent.Term.Filters
+	}
+	if len(filters) > 0 && r.Float64() < cfg.RefinementRate/100 {
+		filter, err := filters.Pick(r)
+		if err != nil {
+			return events, err
+		}
+		if filter != searchEvent.SearchFilters {
+			refinedEvent, err := user.SearchPage(ctx, cfg, searchEvent.Term, searchEvent.Query, filter, 0)
+			if err != nil {
+				return events, err
+			}
+			refinedEvent.FollowUp = true
+			eventTime = s.after(eventTime, 5*time.Second, 30*time.Second)
+			if err := addSearch(refinedEvent, eventTime); err != nil {
+				return events, err
+			}
+			if len(refinedEvent.ObjectIDs) > 0 {
+				searchEvent = refinedEvent
+			}
+		}
+	}
+
+	if len(searchEvent.ObjectIDs) == 0 {
+		return events, nil
+	}
+
+	// Pagination: the user goes to the next page (only if the first one is full).
+	if len(searchEvent.ObjectIDs) == cfg.HitsPerPage && r.Float64() < cfg.PaginationRate/100 {
+		nextPageEvent, err := user.SearchPage(ctx, cfg, searchEvent.Term, searchEvent.Query, searchEvent.SearchFilters, searchEvent.Page+1)
+		if err != nil {
+			return events, err
+		}
+		nextPageEvent.FollowUp = true
+		eventTime = s.after(eventTime, 5*time.Second, 20*time.Second)
+		if err := addSearch(nextPageEvent, eventTime); err != nil {
+			return events, err
+		}
+		if len(nextPageEvent.ObjectIDs) > 0 {
+			searchEvent = nextPageEvent
+		}
+	}
+
+	// Click on one of the hits.
+	clickThroughRate := searchEvent.ClickThroughRate(cfg)
+	if r.Float64() >= clickThroughRate {
+		return events, nil
+	}
+	position, err := searchEvent.PickObjectIDPosition(cfg, r)
+	if err != nil {
+		return events, err
+	}
+	eventTime = s.after(eventTime, 3*time.Second, time.Minute)
+	clickEvent, err := NewClickEvent(user, cfg, eventTime, searchEvent, position)
+	if err != nil {
+		return events, err
+	}
+	events = append(events, *clickEvent)
+
+	// Conversion, only after a click: the probability is adjusted so the conversion rate
+	// (conversions per search) still matches the targeted one.
+	conversionRate := searchEvent.ConversionRate(cfg) / clickThroughRate
+	if r.Float64() >= conversionRate {
+		return events, nil
+	}
+	if len(searchEvent.ObjectIDs) > 1 && r.Float64() >= sameObjectConversionRate {
+		// Conversion on another object of the results (e.g. a similar product).
+		other := r.Intn(len(searchEvent.ObjectIDs) - 1)
+		if other >= position {
+			other++
+		}
+		position = other
+	}
+	eventTime = s.after(eventTime, 30*time.Second, 5*time.Minute)
+	conversionEvent, err := NewConversionEvent(user, cfg, eventTime, searchEvent, position)
+	if err != nil {
+		return events, err
+	}
+	events = append(events, *conversionEvent)
+
+	return events, nil
+}
+
+// endBy shifts the events of a session back, if needed, so the last one happens by the given time.
+// The clicks and conversions are placed minutes after the search: without a backfill window, the
+// session starts about now, and its events would be dated in the future.
+func endBy(events []Event, t time.Time) {
+	last := t
+	for _, event := range events {
+		if event.InsightEvent != nil && event.InsightEvent.Timestamp.After(last) {
+			last = event.InsightEvent.Timestamp
+		}
+	}
+	offset := t.Sub(last)
+	if offset == 0 {
+		return
+	}
+	for _, event := range events {
+		if event.InsightEvent != nil {
+			event.InsightEvent.Timestamp = event.InsightEvent.Timestamp.Add(offset)
+		} else if event.SearchEvent != nil {
+			event.SearchEvent.Time = event.SearchEvent.Time.Add(offset)
+		}
+	}
+}
+
+// CappedConversionTerms returns the search terms with a conversion rate above their click-through rate
+// (their own rates or the global ones, before growth and A/B tests). As the conversions only happen after
+// a click, these terms convert at their click-through rate at most.
+func (cfg *Config) CappedConversionTerms() []SearchTerm {
+	var terms []SearchTerm
+	if cfg.SearchTerms == nil {
+		return terms
+	}
+	for _, term := range cfg.SearchTerms.SearchTerms {
+		clickThroughRate, conversionRate := cfg.ClickThroughRate, cfg.ConversionRate
+		if term.ClickThroughRate != 0 {
+			clickThroughRate = term.ClickThroughRate
+		}
+		if term.ConversionRate != 0 {
+			conversionRate = term.ConversionRate
+		}
+		if !term.NoResults && conversionRate > clickThroughRate {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
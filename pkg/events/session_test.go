@@ -0,0 +1,147 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+func TestSession_Run(t *testing.T) {
+	cfg, _ := newTestConfig(t, 7)
+	cfg.HitsPerPage = 2
+	searchTerms, err := NewSearchTerms("testdata/searches.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A single term without per-term rates.
+	searchTerms.SearchTerms = searchTerms.SearchTerms[2:]
+	searchTerms.SearchTerms[0].Term = ""
+	if err := searchTerms.NewChooser(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.SearchTerms = searchTerms
+
+	sessions := 5000
+	var searches, clicks, conversions, sameObject int
+	for i := 0; i < sessions; i++ {
+		session := &Session{User: NewUser(cfg), Cfg: cfg}
+		events, err := session.Run(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var click *insights.Event
+		for _, event := range events {
+			switch event.EventType() {
+			case eventTypeSearch:
+				if !event.SearchEvent.FollowUp {
+					searches++
+				}
+			case insights.EventTypeClick:
+				clicks++
+				click = event.InsightEvent
+				if click.Positions[0] > cfg.HitsPerPage && event.SearchEvent.Page == 0 {
+					t.Errorf("click position %d outside of the first page", click.Positions[0])
+				}
+			case insights.EventTypeConversion:
+				conversions++
+				if click == nil {
+					t.Fatalf("conversion without a click: %+v", event.InsightEvent)
+				}
+				if event.InsightEvent.QueryID != click.QueryID || event.InsightEvent.Timestamp.Before(click.Timestamp) {
+					t.Errorf("conversion not linked to the click: %+v / %+v", event.InsightEvent, click)
+				}
+				if event.InsightEvent.ObjectIDs[0] == click.ObjectIDs[0] {
+					sameObject++
+				}
+			}
+		}
+	}
+
+	if searches != sessions {
+		t.Errorf("expected %d searches, got %d", sessions, searches)
+	}
+	if ctr := float64(clicks) / float64(searches) * 100; math.Abs(ctr-cfg.ClickThroughRate) > 2 {
+		t.Errorf("expected a CTR close to %.2f%%, got %.2f%%", cfg.ClickThroughRate, ctr)
+	}
+	if cvr := float64(conversions) / float64(searches) * 100; math.Abs(cvr-cfg.ConversionRate) > 2 {
+		t.Errorf("expected a CVR close to %.2f%%, got %.2f%%", cfg.ConversionRate, cvr)
+	}
+	if float64(sameObject) < 0.8*float64(conversions) {
+		t.Errorf("expected most conversions on the clicked object, got %d / %d", sameObject, conversions)
+	}
+}
+
+func TestNewViewEvents(t *testing.T) {
+	cfg, _ := newTestConfig(t, 1)
+	searchEvent := &SearchEvent{}
+	for i := 0; i < 45; i++ {
+		searchEvent.ObjectIDs = append(searchEvent.ObjectIDs, fmt.Sprintf("%d", i))
+	}
+
+	events, err := NewViewEvents(NewUser(cfg), cfg, time.Now(), searchEvent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []int{20, 20, 5}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d view events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if len(event.InsightEvent.ObjectIDs) != expected[i] {
+			t.Errorf("view event #%d: expected %d objectIDs, got %d", i, expected[i], len(event.InsightEvent.ObjectIDs))
+		}
+		if event.InsightEvent.EventType != insights.EventTypeView || event.InsightEvent.EventName != "PLP: Product Viewed" {
+			t.Errorf("view event #%d: unexpected event %+v", i, event.InsightEvent)
+		}
+	}
+}
+
+func TestConfig_CappedConversionTerms(t *testing.T) {
+	cfg := &Config{ClickThroughRate: 20, ConversionRate: 10, SearchTerms: &SearchTerms{SearchTerms: []SearchTerm{
+		{Term: "dress"},
+		{Term: "jacket", ClickThroughRate: 1},
+		{Term: "vest", ClickThroughRate: 5, ConversionRate: 4},
+		{Term: "shoes", ConversionRate: 30},
+		{Term: "qwerty", ClickThroughRate: 1, NoResults: true},
+	}}}
+	var terms []string
+	for _, term := range cfg.CappedConversionTerms() {
+		terms = append(terms, term.Term)
+	}
+	if !reflect.DeepEqual(terms, []string{"jacket", "shoes"}) {
+		t.Errorf("CappedConversionTerms() = %v, want [jacket shoes]", terms)
+	}
+}
+
+func TestEndBy(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	search := &SearchEvent{Time: now}
+	events := []Event{
+		{SearchEvent: search},
+		{InsightEvent: &insights.Event{EventType: insights.EventTypeClick, Timestamp: now.Add(time.Minute)}, SearchEvent: search},
+		{InsightEvent: &insights.Event{EventType: insights.EventTypeConversion, Timestamp: now.Add(5 * time.Minute)}, SearchEvent: search},
+	}
+	endBy(events, now)
+
+	if !search.Time.Equal(now.Add(-5 * time.Minute)) {
+		t.Errorf("expected the search 5 minutes before now, got %s", search.Time)
+	}
+	if got := events[1].InsightEvent.Timestamp; !got.Equal(now.Add(-4 * time.Minute)) {
+		t.Errorf("expected the click 4 minutes before now, got %s", got)
+	}
+	if got := events[2].InsightEvent.Timestamp; !got.Equal(now) {
+		t.Errorf("expected the conversion at now, got %s", got)
+	}
+
+	// A session ending in the past is kept as is.
+	endBy(events, now.Add(time.Hour))
+	if got := events[2].InsightEvent.Timestamp; !got.Equal(now) {
+		t.Errorf("expected the conversion to stay at now, got %s", got)
+	}
+}
@@ -0,0 +1,246 @@
+package events
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+// CSVHeader is the header of the events CSV files, as expected by the Recommend models training UI.
+var CSVHeader = []string{"userToken", "timestamp", "objectID", "eventType", "eventName"}
+
+// EventSink is a destination for the generated events.
+type EventSink interface {
+	Write(events []insights.Event) error
+	Close() error
+}
+
+// InsightsSink sends the events to the Insights API.
+// Events too old to be accepted by the API are written to the Backfill sink if defined, dropped otherwise.
+type InsightsSink struct {
+	Client   *insights.Client
+	Backfill EventSink
+	Sent     int
+	Dropped  int
+}
+
+func (s *InsightsSink) Write(events []insights.Event) error {
+	var recentEvents, tooOldEvents []insights.Event
+	for _, event := range events {
+		if IsTooOld(event.Timestamp) {
+			tooOldEvents = append(tooOldEvents, event)
+		} else {
+			recentEvents = append(recentEvents, event)
+		}
+	}
+	if err := SendEvents(s.Client, recentEvents); err != nil {
+		return err
+	}
+	s.Sent += len(recentEvents)
+	if len(tooOldEvents) == 0 {
+		return nil
+	}
+	if s.Backfill == nil {
+		s.Dropped += len(tooOldEvents)
+		return nil
+	}
+	return s.Backfill.Write(tooOldEvents)
+}
+
+func (s *InsightsSink) Close() error {
+	if s.Backfill != nil {
+		return s.Backfill.Close()
+	}
+	return nil
+}
+
+// NDJSONSink writes the events as newline delimited JSON, in the Insights API format.
+type NDJSONSink struct {
+	closer  io.Closer
+	encoder *json.Encoder
+}
+
+func NewNDJSONSink(w io.Writer) *NDJSONSink {
+	return &NDJSONSink{encoder: json.NewEncoder(w)}
+}
+
+func (s *NDJSONSink) Write(events []insights.Event) error {
+	for _, event := range events {
+		if err := s.encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NDJSONSink) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// CSVSink writes the events as CSV, with the same columns as the `recommend` command CSV files.
+type CSVSink struct {
+	closer    io.Closer
+	csvWriter *csv.Writer
+}
+
+func NewCSVSink(w io.Writer) (*CSVSink, error) {
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(CSVHeader); err != nil {
+		return nil, err
+	}
+	return &CSVSink{csvWriter: csvWriter}, nil
+}
+
+func (s *CSVSink) Write(events []insights.Event) error {
+	for _, event := range events {
+		for _, objectID := range event.ObjectIDs {
+			if err := s.csvWriter.Write([]string{
+				event.UserToken,
+				event.Timestamp.UTC().Format("2006-01-02T15:04:05Z"),
+				objectID,
+				event.EventType,
+				event.EventName,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	s.csvWriter.Flush()
+	return s.csvWriter.Error()
+}
+
+func (s *CSVSink) Close() error {
+	s.csvWriter.Flush()
+	if err := s.csvWriter.Error(); err != nil {
+		return err
+	}
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// MultiSink writes the events to several sinks.
+type MultiSink []EventSink
+
+func (m MultiSink) Write(events []insights.Event) error {
+	for _, sink := range m {
+		if err := sink.Write(events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MultiSink) Close() error {
+	var firstErr error
+	for _, sink := range m {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// createFile creates (or truncates) a file for a file sink.
+func createFile(fileName string) (*os.File, error) {
+	return os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+// NewNDJSONFileSink returns a NDJSONSink writing to the given file.
+func NewNDJSONFileSink(fileName string) (*NDJSONSink, error) {
+	file, err := createFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	sink := NewNDJSONSink(file)
+	sink.closer = file
+	return sink, nil
+}
+
+// NewCSVFileSink returns a CSVSink writing to the given file.
+func NewCSVFileSink(fileName string) (*CSVSink, error) {
+	file, err := createFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := NewCSVSink(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	sink.closer = file
+	return sink, nil
+}
+
+// parseSink splits a sink of the sink flag value into its name and its file name, e.g. `ndjson:./out.ndjson`.
+func parseSink(s string) (name string, fileName string) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+// SinkNames returns the names of the sinks of a comma separated list of sinks, without their file names.
+func SinkNames(spec string) []string {
+	var names []string
+	for _, s := range strings.Split(spec, ",") {
+		if name, _ := parseSink(s); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// NewSinks parses a comma separated list of sinks, e.g. `insights,ndjson:./out.ndjson,csv:./out.csv,stdout`.
+// The insights sink (if any) is returned as well, so the caller can configure it.
+func NewSinks(spec string, client *insights.Client, stdout io.Writer) (MultiSink, *InsightsSink, error) {
+	var sinks MultiSink
+	var insightsSink *InsightsSink
+	for _, s := range strings.Split(spec, ",") {
+		name, fileName := parseSink(s)
+		switch name {
+		case "":
+			continue
+		case "insights":
+			if client == nil {
+				sinks.Close()
+				return nil, nil, fmt.Errorf("the insights sink requires the app-id and api-key flags")
+			}
+			insightsSink = &InsightsSink{Client: client}
+			sinks = append(sinks, insightsSink)
+		case "stdout":
+			sinks = append(sinks, NewNDJSONSink(stdout))
+		case "ndjson", "csv":
+			if fileName == "" {
+				sinks.Close()
+				return nil, nil, fmt.Errorf("missing file name for the %s sink, e.g. %s:./events.%s", name, name, name)
+			}
+			var sink EventSink
+			var err error
+			if name == "ndjson" {
+				sink, err = NewNDJSONFileSink(fileName)
+			} else {
+				sink, err = NewCSVFileSink(fileName)
+			}
+			if err != nil {
+				sinks.Close()
+				return nil, nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			sinks.Close()
+			return nil, nil, fmt.Errorf("unknown sink: %s", name)
+		}
+	}
+	return sinks, insightsSink, nil
+}
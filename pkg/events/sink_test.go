@@ -0,0 +1,89 @@
+package events
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+func TestNewSinks(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		wantSinks int
+		wantErr   bool
+	}{
+		{name: "stdout", spec: "stdout", wantSinks: 1},
+		{name: "files", spec: "ndjson:" + t.TempDir() + "/out.ndjson, csv:" + t.TempDir() + "/out.csv", wantSinks: 2},
+		{name: "missing file name", spec: "ndjson", wantErr: true},
+		{name: "unknown sink", spec: "kafka", wantErr: true},
+		{name: "insights without client", spec: "insights", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sinks, _, err := NewSinks(tt.spec, nil, &bytes.Buffer{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSinks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(sinks) != tt.wantSinks {
+				t.Errorf("NewSinks() = %d sinks, want %d", len(sinks), tt.wantSinks)
+			}
+			sinks.Close()
+		})
+	}
+}
+
+func TestSinkNames(t *testing.T) {
+	names := SinkNames("insights, ndjson:./stdout-archive.ndjson,csv:./out.csv,,stdout")
+	expected := []string{"insights", "ndjson", "csv", "stdout"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("SinkNames() = %v, want %v", names, expected)
+	}
+}
+
+func TestCSVSink_Write(t *testing.T) {
+	buf := bytes.Buffer{}
+	sink, err := NewCSVSink(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = sink.Write([]insights.Event{{
+		EventType: insights.EventTypeConversion,
+		EventName: "PLP: Add to cart",
+		UserToken: "mrs-grim",
+		Timestamp: time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC),
+		ObjectIDs: []string{"1", "2"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "userToken,timestamp,objectID,eventType,eventName\n" +
+		"mrs-grim,2026-07-01T12:00:00Z,1,conversion,PLP: Add to cart\n" +
+		"mrs-grim,2026-07-01T12:00:00Z,2,conversion,PLP: Add to cart\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestIsTooOld(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want bool
+	}{
+		{time.Hour, false},
+		{InsightsMaxEventAge - 2*time.Hour, false},
+		// The events about to expire are too old already.
+		{InsightsMaxEventAge - 30*time.Minute, true},
+		{InsightsMaxEventAge + time.Hour, true},
+	}
+	for _, tt := range tests {
+		if got := IsTooOld(time.Now().Add(-tt.age)); got != tt.want {
+			t.Errorf("IsTooOld(now - %s) = %v, want %v", tt.age, got, tt.want)
+		}
+	}
+}
@@ -1,19 +1,29 @@
 package events
 
 import (
+	"math"
+	"sort"
+
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
-	"github.com/montanaflynn/stats"
 )
 
 const (
 	eventTypeSearch = "search"
+	allTerms        = "ALL"
 )
 
 // Stats store the statistics of the events for a given search term.
+// The events are counted as they are generated, they are not kept in memory.
 type Stats struct {
-	Cfg    *Config
-	Term   string
-	Events []Event
+	Term string
+
+	searches    int
+	clicks      int
+	conversions int
+	views       int
+
+	// clickPositions is the histogram of the click positions.
+	clickPositions map[int]int
 }
 
 type StatsPerTerm struct {
@@ -28,66 +38,84 @@ func (s StatsPerTermList) Len() int           { return len(s) }
 func (s StatsPerTermList) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s StatsPerTermList) Less(i, j int) bool { return s[i].TotalSearches > s[j].TotalSearches }
 
-// NewStatsForTerm creates a new Stats object for a given search term.
-func NewStatsForTerm(term string, events []Event) *StatsPerTerm {
-	var eventsForTerm []Event
-	for _, event := range events {
-		if event.SearchEvent.Term.Term == term || term == "ALL" {
-			eventsForTerm = append(eventsForTerm, event)
-		}
+// NewStats creates a new empty Stats object for a given search term.
+func NewStats(term string) *Stats {
+	return &Stats{
+		Term:           term,
+		clickPositions: make(map[int]int),
 	}
-	stats := &StatsPerTerm{
-		Stats: Stats{
-			Term:   term,
-			Events: eventsForTerm,
-		},
-	}
-	stats.TotalSearches = stats.Stats.TotalEventsOfType(eventTypeSearch)
-	return stats
 }
 
-func (s *Stats) EventsOfType(eventType string) []Event {
-	var events []Event
-	for _, event := range s.Events {
-		if event.EventType() == eventType {
-			events = append(events, event)
+// Add counts an event.
+func (s *Stats) Add(event Event) {
+	switch event.EventType() {
+	case eventTypeSearch:
+		// The follow-up searches of the sessions are not counted.
+		if !event.SearchEvent.FollowUp {
+			s.searches++
 		}
+	case insights.EventTypeClick:
+		s.clicks++
+		s.clickPositions[event.InsightEvent.Positions[0]]++
+	case insights.EventTypeConversion:
+		s.conversions++
+	case insights.EventTypeView:
+		s.views++
 	}
-	return events
-}
-
-func (s *Stats) TotalEventsOfType(eventType string) int {
-	return len(s.EventsOfType(eventType))
-}
-
-func (s *Stats) ClickPositionList() []float64 {
-	var positions []float64
-	for _, event := range s.EventsOfType(insights.EventTypeClick) {
-		positions = append(positions, float64(event.InsightEvent.Positions[0]))
-	}
-	return positions
 }
 
 func (s *Stats) MeanClickPosition() float64 {
-	mean, _ := stats.Mean(s.ClickPositionList())
-	return mean
+	if s.clicks == 0 {
+		return math.NaN()
+	}
+	sum := 0
+	for position, count := range s.clickPositions {
+		sum += position * count
+	}
+	return float64(sum) / float64(s.clicks)
 }
 
 func (s *Stats) MedianClickPosition() float64 {
-	median, _ := stats.Median(s.ClickPositionList())
-	return median
+	if s.clicks == 0 {
+		return math.NaN()
+	}
+	positions := make([]int, 0, len(s.clickPositions))
+	for position := range s.clickPositions {
+		positions = append(positions, position)
+	}
+	sort.Ints(positions)
+
+	// nth returns the nth (0 based) click position.
+	nth := func(n int) int {
+		for _, position := range positions {
+			n -= s.clickPositions[position]
+			if n < 0 {
+				return position
+			}
+		}
+		return positions[len(positions)-1]
+	}
+	if s.clicks%2 == 1 {
+		return float64(nth(s.clicks / 2))
+	}
+	return float64(nth(s.clicks/2-1)+nth(s.clicks/2)) / 2
 }
 
+// TotalSearches returns the number of searches, not counting the follow-up searches of the sessions.
 func (s *Stats) TotalSearches() int {
-	return s.TotalEventsOfType(eventTypeSearch)
+	return s.searches
 }
 
 func (s *Stats) TotalClicks() int {
-	return s.TotalEventsOfType(insights.EventTypeClick)
+	return s.clicks
+}
+
+func (s *Stats) TotalViews() int {
+	return s.views
 }
 
 func (s *Stats) TotalConversions() int {
-	return s.TotalEventsOfType(insights.EventTypeConversion)
+	return s.conversions
 }
 
 func (s *Stats) ClickThroughRatePercent() float64 {
@@ -97,3 +125,48 @@ func (s *Stats) ClickThroughRatePercent() float64 {
 func (s *Stats) ConversionRatePercent() float64 {
 	return float64(s.TotalConversions()) / float64(s.TotalSearches()) * 100
 }
+
+// StatsCollector computes the stats of all the events and of each search term, incrementally.
+type StatsCollector struct {
+	all   *Stats
+	terms []*Stats
+	index map[string]*Stats
+}
+
+// NewStatsCollector creates a StatsCollector for the given search terms.
+func NewStatsCollector(searchTerms []SearchTerm) *StatsCollector {
+	c := &StatsCollector{
+		all:   NewStats(allTerms),
+		index: make(map[string]*Stats),
+	}
+	for _, term := range searchTerms {
+		if _, ok := c.index[term.Term]; ok {
+			continue
+		}
+		stats := NewStats(term.Term)
+		c.terms = append(c.terms, stats)
+		c.index[term.Term] = stats
+	}
+	return c
+}
+
+// Add counts an event, globally and for its search term.
+func (c *StatsCollector) Add(event Event) {
+	c.all.Add(event)
+	if stats, ok := c.index[event.SearchEvent.Term.Term]; ok {
+		stats.Add(event)
+	}
+}
+
+// List returns the stats, sorted by number of searches.
+func (c *StatsCollector) List() StatsPerTermList {
+	list := make(StatsPerTermList, 0, len(c.terms)+1)
+	for _, stats := range append([]*Stats{c.all}, c.terms...) {
+		list = append(list, &StatsPerTerm{
+			TotalSearches: stats.TotalSearches(),
+			Stats:         *stats,
+		})
+	}
+	sort.Sort(list)
+	return list
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"math"
+	"testing"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+func clickAt(term string, position int) Event {
+	return Event{
+		InsightEvent: &insights.Event{EventType: insights.EventTypeClick, Positions: []int{position}},
+		SearchEvent:  &SearchEvent{Term: SearchTerm{Term: term}},
+	}
+}
+
+func TestStats_ClickPosition(t *testing.T) {
+	tests := []struct {
+		name       string
+		positions  []int
+		wantMean   float64
+		wantMedian float64
+	}{
+		{name: "no clicks", wantMean: math.NaN(), wantMedian: math.NaN()},
+		{name: "odd number of clicks", positions: []int{3, 1, 8}, wantMean: 4, wantMedian: 3},
+		{name: "even number of clicks", positions: []int{1, 1, 2, 5}, wantMean: 2.25, wantMedian: 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := NewStats("jacket")
+			for _, position := range tt.positions {
+				stats.Add(clickAt("jacket", position))
+			}
+			if mean := stats.MeanClickPosition(); mean != tt.wantMean && !(math.IsNaN(mean) && math.IsNaN(tt.wantMean)) {
+				t.Errorf("Stats.MeanClickPosition() = %v, want %v", mean, tt.wantMean)
+			}
+			if median := stats.MedianClickPosition(); median != tt.wantMedian && !(math.IsNaN(median) && math.IsNaN(tt.wantMedian)) {
+				t.Errorf("Stats.MedianClickPosition() = %v, want %v", median, tt.wantMedian)
+			}
+		})
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	c := NewStatsCollector([]SearchTerm{{Term: "jacket"}, {Term: "dress"}, {Term: "jacket"}})
+	c.Add(Event{SearchEvent: &SearchEvent{Term: SearchTerm{Term: "jacket"}}})
+	c.Add(Event{SearchEvent: &SearchEvent{Term: SearchTerm{Term: "jacket"}, FollowUp: true}})
+	c.Add(clickAt("jacket", 2))
+	c.Add(Event{SearchEvent: &SearchEvent{Term: SearchTerm{Term: "unknown"}}})
+
+	list := c.List()
+	if len(list) != 3 {
+		t.Fatalf("expected 3 stats (ALL + 2 terms), got %d", len(list))
+	}
+	expected := []struct {
+		term     string
+		searches int
+		clicks   int
+	}{
+		{term: "ALL", searches: 2, clicks: 1},
+		{term: "jacket", searches: 1, clicks: 1},
+		{term: "dress", searches: 0, clicks: 0},
+	}
+	for i, e := range expected {
+		if list[i].Stats.Term != e.term || list[i].TotalSearches != e.searches || list[i].Stats.TotalClicks() != e.clicks {
+			t.Errorf("stats #%d: expected %+v, got %s: %d searches, %d clicks", i, e, list[i].Stats.Term, list[i].TotalSearches, list[i].Stats.TotalClicks())
+		}
+	}
+}
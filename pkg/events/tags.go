@@ -3,6 +3,7 @@ package events
 import (
 	"encoding/json"
 	"io/ioutil"
+	"math/rand"
 	"os"
 
 	wr "github.com/mroth/weightedrand"
@@ -17,16 +18,16 @@ type Tags struct {
 	Chooser *wr.Chooser
 }
 
-func (t *Tags) Pick() string {
-	return t.Chooser.Pick().(string)
+func (t *Tags) Pick(r *rand.Rand) string {
+	return t.Chooser.PickSource(r).(string)
 }
 
 func NewTags(values map[string]int) (*Tags, error) {
 	var choices []wr.Choice
-	for k, v := range values {
+	for _, k := range sortedWeightKeys(values) {
 		choices = append(choices, wr.Choice{
 			Item:   k,
-			Weight: uint(v),
+			Weight: uint(values[k]),
 		})
 	}
 	chooser, err := wr.NewChooser(choices...)
@@ -54,10 +55,14 @@ func LoadTags(fileName string) ([]TagsCollection, error) {
 	if err := json.Unmarshal(fileBytes, &values); err != nil {
 		return nil, err
 	}
+	return NewTagsCollection(values)
+}
 
+// NewTagsCollection returns the tags collections from their weighted values, by collection name.
+func NewTagsCollection(values map[string]map[string]int) ([]TagsCollection, error) {
 	tagsCollection := make([]TagsCollection, 0, len(values))
-	for k, v := range values {
-		tags, err := NewTags(v)
+	for _, k := range sortedGroupKeys(values) {
+		tags, err := NewTags(values[k])
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// TokenRecorder records the user tokens of the generated events in a file, one per line,
+// so the data of the fake users can be deleted afterwards (see the cleanup command).
+// The tokens are appended to the file: it holds the tokens of every recorded run.
+type TokenRecorder struct {
+	file   *os.File
+	writer *bufio.Writer
+	seen   map[string]bool
+}
+
+// NewTokenRecorder opens (or creates) a tokens file. The tokens already in the file are not recorded twice.
+func NewTokenRecorder(fileName string) (*TokenRecorder, error) {
+	tokens, err := LoadTokens(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r := &TokenRecorder{file: file, writer: bufio.NewWriter(file), seen: make(map[string]bool)}
+	for _, token := range tokens {
+		r.seen[token] = true
+	}
+	return r, nil
+}
+
+// Record adds a token to the file, unless it is already recorded.
+func (r *TokenRecorder) Record(token string) error {
+	if token == "" || r.seen[token] {
+		return nil
+	}
+	r.seen[token] = true
+	_, err := r.writer.WriteString(token + "\n")
+	return err
+}
+
+// Flush writes the recorded tokens to the file.
+func (r *TokenRecorder) Flush() error {
+	return r.writer.Flush()
+}
+
+func (r *TokenRecorder) Close() error {
+	err := r.writer.Flush()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// LoadTokens reads a tokens file, without the duplicates, the blank lines and the `#` comments.
+func LoadTokens(fileName string) ([]string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tokens := make([]string, 0)
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		token := strings.TrimSpace(scanner.Text())
+		if token == "" || strings.HasPrefix(token, "#") || seen[token] {
+			continue
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+	return tokens, scanner.Err()
+}
+
+// SaveTokens writes a tokens file, replacing its content.
+func SaveTokens(fileName string, tokens []string) error {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString(token + "\n")
+	}
+	return ioutil.WriteFile(fileName, []byte(b.String()), 0644)
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTokenRecorder(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "user-tokens.txt")
+	if err := ioutil.WriteFile(fileName, []byte("# previous run\nuser-1\n\nuser-2\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r, err := NewTokenRecorder(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, token := range []string{"user-2", "user-3", "", "user-3", "user-1"} {
+		if err := r.Record(token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tokens, err := LoadTokens(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"user-1", "user-2", "user-3"}; !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("LoadTokens() = %v, want %v", tokens, expected)
+	}
+}
+
+func TestRun_RecordTokens(t *testing.T) {
+	cfg, sink := newTestConfig(t, 1)
+	fileName := filepath.Join(t.TempDir(), "user-tokens.txt")
+	var err error
+	cfg.Tokens, err = NewTokenRecorder(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := Run(context.Background(), cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cfg.Tokens.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every user token of the events sent is recorded, once.
+	expected := make(map[string]bool)
+	for _, event := range sink.events {
+		expected[event.UserToken] = true
+	}
+	tokens, err := LoadTokens(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != len(expected) {
+		t.Errorf("expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for _, token := range tokens {
+		if !expected[token] {
+			t.Errorf("unexpected token %s", token)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	// suggestionPickRate is the probability for a typing user to pick the query in the suggestions
+	// before typing it entirely.
+	suggestionPickRate = 0.3
+	// minSuggestionPrefix is the number of keys typed before picking a suggestion.
+	minSuggestionPrefix = 2
+	// maxTypoKeys is the maximum number of keys typed after a typo, before the user notices it.
+	maxTypoKeys = 2
+)
+
+// TypedPrefixes returns the successive queries of a user typing the given query in a search-as-you-type UI,
+// one per keystroke (including the backspaces correcting a typo), without the final query.
+// The user can pick the query in the suggestions before typing it entirely.
+// typoRate is the probability of a typo (an adjacent key) while typing the query.
+func TypedPrefixes(r *rand.Rand, query string, typoRate float64) []string {
+	keys := []rune(query)
+	typed := len(keys)
+	if len(keys) > minSuggestionPrefix+1 && r.Float64() < suggestionPickRate {
+		typed = minSuggestionPrefix + r.Intn(len(keys)-minSuggestionPrefix-1)
+	}
+	typo := -1
+	if typed > 0 && r.Float64() < typoRate {
+		typo = r.Intn(typed)
+	}
+
+	prefixes := make([]string, 0, typed)
+	for i := 0; i < typed; i++ {
+		if i == typo {
+			if wrong := typoKey(r, keys[i]); wrong != 0 {
+				// The user notices the typo after a few more keys and erases up to it.
+				extra := r.Intn(maxTypoKeys + 1)
+				if i+extra >= typed {
+					extra = typed - i - 1
+				}
+				mistyped := append(append([]rune{}, keys[:i]...), wrong)
+				prefixes = append(prefixes, string(mistyped))
+				for _, key := range keys[i+1 : i+1+extra] {
+					mistyped = append(mistyped, key)
+					prefixes = append(prefixes, string(mistyped))
+				}
+				for len(mistyped) > i {
+					mistyped = mistyped[:len(mistyped)-1]
+					if len(mistyped) > 0 {
+						prefixes = append(prefixes, string(mistyped))
+					}
+				}
+			}
+		}
+		if i < len(keys)-1 {
+			prefixes = append(prefixes, string(keys[:i+1]))
+		}
+	}
+	return prefixes
+}
+
+// TypeQuery does the searches of a user typing the query in a search-as-you-type UI,
+// before the search of the full query. The keystrokes are spaced by about cfg.KeystrokeDelay.
+func (u *User) TypeQuery(ctx context.Context, cfg *Config, query string, filter string) error {
+	for _, prefix := range TypedPrefixes(u.Rand, query, cfg.TypoRate/100) {
+		if cfg.KeystrokeDelay > 0 {
+			delay := cfg.KeystrokeDelay/2 + time.Duration(u.Rand.Int63n(int64(cfg.KeystrokeDelay)))
+			if err := sleep(ctx, delay); err != nil {
+				return err
+			}
+		}
+		if _, err := u.SearchPage(ctx, cfg, SearchTerm{Term: query}, prefix, filter, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestAdjacentKeys(t *testing.T) {
+	tests := []struct {
+		key  rune
+		want string
+	}{
+		{key: 'q', want: "12wa"},
+		{key: 'G', want: "tyfhvb"},
+		{key: 'm', want: "jkn"},
+		{key: ' ', want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.key), func(t *testing.T) {
+			got := adjacentKeys(tt.key)
+			if len(got) != len(tt.want) {
+				t.Fatalf("adjacentKeys(%q) = %q, want the keys %q", tt.key, string(got), tt.want)
+			}
+			for _, key := range got {
+				if !strings.ContainsRune(tt.want, key) {
+					t.Errorf("adjacentKeys(%q) = %q, want the keys %q", tt.key, string(got), tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTypedPrefixes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	query := "black dress"
+	typos := 0
+	for i := 0; i < 1000; i++ {
+		prefixes := TypedPrefixes(r, query, 0.5)
+		if len(prefixes) == 0 {
+			t.Fatalf("expected some prefixes")
+		}
+
+		// Each search is one keystroke away from the previous one: a key typed or erased.
+		previous := ""
+		typo := false
+		for _, prefix := range append(prefixes, query) {
+			// The last search can be a suggestion picked by the user.
+			if previous != prefixes[len(prefixes)-1] && !oneKeystrokeAway(previous, prefix) {
+				t.Fatalf("%q is not one keystroke away from %q in %q", prefix, previous, prefixes)
+			}
+			if !strings.HasPrefix(query, prefix) {
+				typo = true
+			}
+			previous = prefix
+		}
+		if typo {
+			typos++
+		}
+	}
+
+	// Half of the queries with a typo, except the ones with a typo on the space key.
+	if typos < 400 || typos > 500 {
+		t.Errorf("expected about 45%% of the typed queries with a typo, got %d / 1000", typos)
+	}
+}
+
+// oneKeystrokeAway returns true if b is typed from a with one key, or one backspace
+// (or a backspace and a key, for a typo on the first key).
+func oneKeystrokeAway(a string, b string) bool {
+	switch len(b) - len(a) {
+	case 1:
+		return strings.HasPrefix(b, a)
+	case -1:
+		return strings.HasPrefix(a, b)
+	case 0:
+		return len(a) == 1
+	}
+	return false
+}
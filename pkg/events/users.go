@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,15 +10,24 @@ import (
 	"sync"
 
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
-	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
 )
 
 type User struct {
-	Token string
-	Tags  []string
+	// Description of the persona (not used in the tool, but good to have).
+	Description string `json:"description,omitempty"`
 
-	Terms   []string
-	Filters Filters
+	Token string   `json:"token"`
+	Tags  []string `json:"tags,omitempty"`
+
+	Terms   []string `json:"terms,omitempty"`
+	Filters Filters  `json:"filters,omitempty"`
+
+	// Rand is the user's own source of randomness, so the generated events
+	// don't depend on the order in which the users are processed.
+	Rand *rand.Rand `json:"-"`
+
+	// Index is the position of the user in the run, used to checkpoint its progress.
+	Index int `json:"-"`
 }
 
 func (u *User) String() string {
@@ -39,26 +49,23 @@ func (u *User) GetSearchOptions(cfg *Config) []interface{} {
 func (u *User) GetSearchFilter(searchTerm *SearchTerm) (string, error) {
 	// User don't have any predefined filters (random user case)
 	if len(u.Filters) == 0 {
-		filter, err := searchTerm.Filters.Pick()
+		filter, err := searchTerm.Filters.Pick(u.Rand)
 		if err != nil {
 			return "", err
 		}
 		return filter, nil
 	}
 	// User has predefined filters (persona case)
-	return u.Filters.Pick()
+	return u.Filters.Pick(u.Rand)
 }
 
-// Search returns a SearchEvent for the user.
+// Search returns a SearchEvent for the user, the first search of a session.
 // If the user have his own search terms, it will be used instead of the global ones.
-func (u *User) Search(cfg *Config) (*SearchEvent, error) {
-	searchOpts := u.GetSearchOptions(cfg)
-	searchOpts = append(searchOpts, opt.HitsPerPage(cfg.HitsPerPage))
-
+func (u *User) Search(ctx context.Context, cfg *Config) (*SearchEvent, error) {
 	// Search term
-	searchTerm := cfg.SearchTerms.Pick()
+	searchTerm := cfg.SearchTerms.Pick(u.Rand)
 	if len(u.Terms) > 0 {
-		searchTerm = SearchTerm{Term: u.Terms[rand.Intn(len(u.Terms))]}
+		searchTerm = SearchTerm{Term: u.Terms[u.Rand.Intn(len(u.Terms))]}
 	}
 
 	// Eventual filters
@@ -66,57 +73,68 @@ func (u *User) Search(cfg *Config) (*SearchEvent, error) {
 	if err != nil {
 		return nil, err
 	}
-	if filter != "" {
-		searchOpts = append(searchOpts, opt.Filters(filter))
-	}
-
-	// Need to add the `GetRankingInfo` to identify the A/B test variant ID
-	if cfg.ABTest.VariantID != 0 {
-		searchOpts = append(searchOpts, opt.GetRankingInfo(true))
-	}
 
-	var res search.QueryRes
-
-	if len(searchTerm.Synonyms) == 0 {
-		// Not a synonyms case
-		res, err = cfg.SearchIndex.Search(searchTerm.Term, searchOpts...)
-		if err != nil {
-			return nil, err
-		}
-	} else {
+	query := searchTerm.Term
+	if len(searchTerm.Synonyms) > 0 {
 		// Eventually do the search with the low recall term (lowering the traffic on the low recall term)
-		if rand.Intn(100) < 30 {
-			_, err = cfg.SearchIndex.Search(searchTerm.Term, searchOpts...)
-			if err != nil {
+		if u.Rand.Intn(100) < 30 {
+			if _, err := u.SearchPage(ctx, cfg, searchTerm, searchTerm.Term, filter, 0); err != nil {
 				return nil, err
 			}
 		}
 		// Trigger the Dynamic Synonyms by doing directly a search with one the synonym.
-		res, err = cfg.SearchIndex.Search(searchTerm.PickSynonym(), searchOpts...)
-		if err != nil {
+		query = searchTerm.PickSynonym(u.Rand)
+	}
+
+	// Query variations: the query as typed by the user (the stats still use the search term).
+	variations := &cfg.QueryVariations
+	if searchTerm.Variations != nil {
+		variations = searchTerm.Variations
+	}
+	query = variations.Mutate(u.Rand, query)
+
+	// Search-as-you-type: the searches of the keystrokes come first.
+	if cfg.TypingRate > 0 && u.Rand.Float64() < cfg.TypingRate/100 {
+		if err := u.TypeQuery(ctx, cfg, query, filter); err != nil {
 			return nil, err
 		}
 	}
+	return u.SearchPage(ctx, cfg, searchTerm, query, filter, 0)
+}
 
-	// Store the objectIDs so we can click / convert on them later.
-	objectIDs := make([]string, 0, res.NbHits)
-	for _, hit := range res.Hits {
-		objectIDs = append(objectIDs, hit["objectID"].(string))
+// SearchPage does a search query for the given search term and returns the matching SearchEvent.
+// The query can differ from the search term (synonyms), the page starts at 0.
+func (u *User) SearchPage(ctx context.Context, cfg *Config, searchTerm SearchTerm, query string, filter string, page int) (*SearchEvent, error) {
+	searchOpts := u.GetSearchOptions(cfg)
+	searchOpts = append(searchOpts, ctx, opt.HitsPerPage(cfg.HitsPerPage))
+	if page > 0 {
+		searchOpts = append(searchOpts, opt.Page(page))
+	}
+	if filter != "" {
+		searchOpts = append(searchOpts, opt.Filters(filter))
 	}
 
-	searchEvent := &SearchEvent{
-		Term:            searchTerm,
-		ObjectIDs:       objectIDs,
-		QueryID:         res.QueryID,
-		ABTestVariantID: res.ABTestVariantID,
+	// Need to add the `GetRankingInfo` to identify the A/B test variant ID
+	if cfg.ABTest.Enabled() {
+		searchOpts = append(searchOpts, opt.GetRankingInfo(true))
 	}
 
-	if filter != "" {
-		searchEvent.Filters = []string{filter}
+	res, err := cfg.SearchIndex.Search(query, searchOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the objectIDs so we can click / convert on them later.
+	objectIDs := make([]string, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		objectIDs = append(objectIDs, hit["objectID"].(string))
 	}
 
 	return &SearchEvent{
 		Term:            searchTerm,
+		Query:           query,
+		SearchFilters:   filter,
+		Page:            page,
 		ObjectIDs:       objectIDs,
 		QueryID:         res.QueryID,
 		ABTestVariantID: res.ABTestVariantID,
@@ -126,10 +144,11 @@ func (u *User) Search(cfg *Config) (*SearchEvent, error) {
 // NewUser returns a random new user.
 func NewUser(cfg *Config) *User {
 	user := &User{
-		Token: fmt.Sprintf("%d", rand.Int63()),
+		Token: fmt.Sprintf("%d", cfg.Rand.Int63()),
+		Rand:  rand.New(rand.NewSource(cfg.Rand.Int63())),
 	}
 	for v := range cfg.TagsCollection {
-		user.Tags = append(user.Tags, cfg.TagsCollection[v].Tags.Pick())
+		user.Tags = append(user.Tags, cfg.TagsCollection[v].Tags.Pick(cfg.Rand))
 	}
 	return user
 }
@@ -151,19 +170,38 @@ func NewUsersFromFile(cfg *Config, fileName string) ([]*User, error) {
 	return users, nil
 }
 
-// GenerateUsers generates a list of users.
-func GenerateUsers(wg *sync.WaitGroup, cfg *Config) <-chan *User {
+// GenerateUsers generates a list of users, until the context is canceled.
+// When resuming from a checkpoint, the users are generated the same way but the ones
+// with all their sessions done are skipped.
+func GenerateUsers(ctx context.Context, wg *sync.WaitGroup, cfg *Config) <-chan *User {
 	ch := make(chan *User)
 	go func() {
+		defer close(ch)
+		send := func(user *User) bool {
+			if cfg.Checkpoint != nil && cfg.Checkpoint.SessionsDone(user.Index) >= cfg.SearchesPerUser {
+				return true
+			}
+			select {
+			case ch <- user:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
 		for i := 0; i < cfg.NumberOfUsers; i++ {
-			ch <- NewUser(cfg)
+			user := NewUser(cfg)
+			user.Index = i
+			if !send(user) {
+				return
+			}
 		}
-		if cfg.PersonaUsers != nil {
-			for _, user := range cfg.PersonaUsers {
-				ch <- user
+		for i, user := range cfg.PersonaUsers {
+			user.Rand = rand.New(rand.NewSource(cfg.Rand.Int63()))
+			user.Index = cfg.NumberOfUsers + i
+			if !send(user) {
+				return
 			}
 		}
-		close(ch)
 	}()
 	return ch
 }
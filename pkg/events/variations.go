@@ -0,0 +1,175 @@
+package events
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"unicode"
+
+	wr "github.com/mroth/weightedrand"
+)
+
+// The kinds of query variations.
+const (
+	variationTypo      = "typo"
+	variationPlural    = "plural"
+	variationCasing    = "casing"
+	variationWordOrder = "word_order"
+)
+
+// QueryVariations mutate the queries as real users would type them.
+// Rate is the percentage of the searches with a mutated query, the kind of variation
+// being picked with the weights.
+type QueryVariations struct {
+	Rate      float64 `json:"rate"`
+	Typo      uint    `json:"typo"`
+	Plural    uint    `json:"plural"`
+	Casing    uint    `json:"casing"`
+	WordOrder uint    `json:"word_order"`
+}
+
+// ParseQueryVariationsWeights parses the weights of the variations from a comma separated list
+// of `<variation>:<weight>` (e.g. `typo:3,plural:2,casing:1,word_order:1`).
+func ParseQueryVariationsWeights(spec string) (QueryVariations, error) {
+	var v QueryVariations
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return v, fmt.Errorf("invalid query variation %q: expected <variation>:<weight>", item)
+		}
+		weight, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return v, fmt.Errorf("invalid query variation weight %q: %v", item, err)
+		}
+		switch parts[0] {
+		case variationTypo:
+			v.Typo = uint(weight)
+		case variationPlural:
+			v.Plural = uint(weight)
+		case variationCasing:
+			v.Casing = uint(weight)
+		case variationWordOrder:
+			v.WordOrder = uint(weight)
+		default:
+			return v, fmt.Errorf("unknown query variation: %s", parts[0])
+		}
+	}
+	return v, nil
+}
+
+// Mutate returns the query eventually mutated by one of the variations.
+// The query is returned as is if none of the variations applies.
+func (v *QueryVariations) Mutate(r *rand.Rand, query string) string {
+	if v == nil || v.Rate <= 0 || r.Float64() >= v.Rate/100 {
+		return query
+	}
+	chooser, err := wr.NewChooser(
+		wr.Choice{Item: variationTypo, Weight: v.Typo},
+		wr.Choice{Item: variationPlural, Weight: v.Plural},
+		wr.Choice{Item: variationCasing, Weight: v.Casing},
+		wr.Choice{Item: variationWordOrder, Weight: v.WordOrder},
+	)
+	if err != nil {
+		// No weights
+		return query
+	}
+	switch chooser.PickSource(r).(string) {
+	case variationTypo:
+		return typoQuery(r, query)
+	case variationPlural:
+		return pluralQuery(query)
+	case variationCasing:
+		return casingQuery(r, query)
+	case variationWordOrder:
+		return wordOrderQuery(r, query)
+	}
+	return query
+}
+
+// typoQuery replaces one of the letters of the query by an adjacent key.
+func typoQuery(r *rand.Rand, query string) string {
+	keys := []rune(query)
+	var positions []int
+	for i, key := range keys {
+		if len(adjacentKeys(key)) > 0 {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) == 0 {
+		return query
+	}
+	i := positions[r.Intn(len(positions))]
+	keys[i] = typoKey(r, keys[i])
+	return string(keys)
+}
+
+// pluralQuery switches the last word of the query between its singular and plural forms
+// (english regular forms only).
+func pluralQuery(query string) string {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return query
+	}
+	last := words[len(words)-1]
+	lower := strings.ToLower(last)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(last) > 3:
+		last = last[:len(last)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		last = last[:len(last)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") && !strings.HasSuffix(lower, "us"):
+		last = last[:len(last)-1]
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		last += "es"
+	case strings.HasSuffix(lower, "y") && len(last) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		last = last[:len(last)-1] + "ies"
+	default:
+		last += "s"
+	}
+	words[len(words)-1] = last
+	return strings.Join(words, " ")
+}
+
+// casingQuery changes the casing of the query: capitalized, title case or upper case.
+func casingQuery(r *rand.Rand, query string) string {
+	switch r.Intn(3) {
+	case 0:
+		keys := []rune(strings.ToLower(query))
+		if len(keys) > 0 {
+			keys[0] = unicode.ToUpper(keys[0])
+		}
+		return string(keys)
+	case 1:
+		words := strings.Fields(strings.ToLower(query))
+		for i, word := range words {
+			keys := []rune(word)
+			keys[0] = unicode.ToUpper(keys[0])
+			words[i] = string(keys)
+		}
+		return strings.Join(words, " ")
+	default:
+		return strings.ToUpper(query)
+	}
+}
+
+// wordOrderQuery swaps two words of the query.
+func wordOrderQuery(r *rand.Rand, query string) string {
+	words := strings.Fields(query)
+	if len(words) < 2 {
+		return query
+	}
+	i := r.Intn(len(words))
+	j := r.Intn(len(words) - 1)
+	if j >= i {
+		j++
+	}
+	words[i], words[j] = words[j], words[i]
+	return strings.Join(words, " ")
+}
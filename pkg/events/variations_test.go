@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseQueryVariationsWeights(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    QueryVariations
+		wantErr bool
+	}{
+		{spec: "", want: QueryVariations{}},
+		{spec: "typo:3, plural:2,casing:1,word_order:1", want: QueryVariations{Typo: 3, Plural: 2, Casing: 1, WordOrder: 1}},
+		{spec: "typo", wantErr: true},
+		{spec: "typo:-1", wantErr: true},
+		{spec: "synonym:1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseQueryVariationsWeights(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQueryVariationsWeights() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseQueryVariationsWeights() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluralQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "black dress", want: "black dresses"},
+		{query: "black dresses", want: "black dress"},
+		{query: "jacket", want: "jackets"},
+		{query: "jackets", want: "jacket"},
+		{query: "watch", want: "watches"},
+		{query: "berry", want: "berries"},
+		{query: "berries", want: "berry"},
+		{query: "toy", want: "toys"},
+		{query: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := pluralQuery(tt.query); got != tt.want {
+				t.Errorf("pluralQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryVariations_Mutate(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	query := "black leather jacket"
+	tests := []struct {
+		name       string
+		variations QueryVariations
+		check      func(string) bool
+	}{
+		{name: "typo", variations: QueryVariations{Rate: 100, Typo: 1}, check: func(q string) bool {
+			return len(q) == len(query) && q != query
+		}},
+		{name: "casing", variations: QueryVariations{Rate: 100, Casing: 1}, check: func(q string) bool {
+			return strings.EqualFold(q, query) && q != query
+		}},
+		{name: "word order", variations: QueryVariations{Rate: 100, WordOrder: 1}, check: func(q string) bool {
+			return len(q) == len(query) && q != query && strings.Contains(q, "leather")
+		}},
+		{name: "no variation", variations: QueryVariations{Rate: 0, Typo: 1}, check: func(q string) bool {
+			return q == query
+		}},
+		{name: "no weights", variations: QueryVariations{Rate: 100}, check: func(q string) bool {
+			return q == query
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if got := tt.variations.Mutate(r, query); !tt.check(got) {
+					t.Fatalf("QueryVariations.Mutate() = %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_QueryVariations(t *testing.T) {
+	cfg, _ := newTestConfig(t, 1)
+	cfg.QueryVariations = QueryVariations{Rate: 100, Typo: 1, Plural: 1, Casing: 1, WordOrder: 1}
+	stats, err := Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The searches of the mutated queries are counted for their search term.
+	total := 0
+	for _, s := range stats[1:] {
+		total += s.TotalSearches
+	}
+	if total != stats[0].TotalSearches || total != cfg.NumberOfUsers*cfg.SearchesPerUser {
+		t.Errorf("expected %d searches for the search terms, got %d (%d in total)", cfg.NumberOfUsers*cfg.SearchesPerUser, total, stats[0].TotalSearches)
+	}
+}
@@ -1,30 +1,175 @@
 package recommend
 
 import (
-	"encoding/csv"
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+	"github.com/algolia/fake-insights-generator/pkg/events"
 	"github.com/algolia/fake-insights-generator/pkg/iostreams"
 	"github.com/go-gota/gota/dataframe"
+	"github.com/go-gota/gota/series"
 	"github.com/google/uuid"
 )
 
+// Defaults of the recommend events generation.
+const (
+	DefaultRecommendFile        = "recommend.json"
+	DefaultClicksPerObject      = 15
+	DefaultConversionsPerObject = 50
+	DefaultWindow               = 90 * 24 * time.Hour
+	DefaultSimilarFile          = "events-similar.csv"
+	DefaultFBTFile              = "events-fbt.csv"
+)
+
 type Config struct {
 	IO *iostreams.IOStreams
 
+	// Rand is the source of all the random choices, seeded with the --seed flag for reproducible runs.
+	Rand *rand.Rand
+
 	SearchIndex    *search.Index
 	InsightsClient *insights.Client
+
+	// Recommend is the recommend configuration, loaded from RecommendFile if not set.
+	Recommend     *Recommend
+	RecommendFile string
+
+	// FacetName is the facet of the categories, instead of the one of the recommend configuration.
+	FacetName string
+	// Multipliers of the number of events of the objects of some categories, merged with the
+	// multipliers of the recommend configuration.
+	Multipliers map[string]float64
+
+	// Number of click events (similar items) and of conversion events (frequently bought together)
+	// per object, spread over the Window before now.
+	ClicksPerObject      int
+	ConversionsPerObject int
+	Window               time.Duration
+
+	// CSV files of the similar items (clicks) and frequently bought together (conversions) events.
+	SimilarFile string
+	FBTFile     string
+
+	// RelatedAttributes are the attributes weights of the related products model,
+	// instead of the ones of the recommend configuration.
+	RelatedAttributes map[string]float64
+	// RelatedFile is the CSV file of the related products events.
+	RelatedFile string
+
+	// Trending replaces the trending configuration of the recommend configuration, if set.
+	Trending *Trending
+	// TrendingFile is the CSV file of the trending events.
+	TrendingFile string
+
+	// Send sends the events recent enough for the Insights API instead of writing them to the CSV files.
+	Send bool
+}
+
+// Output is the destination of the events of a model.
+type Output struct {
+	Model string
+	File  string
+	// Sent is the number of events sent to the Insights API, Written the number of events written to File.
+	Sent    int
+	Written int
 }
 
 type Recommend struct {
-	FacetName string
-	FBT       map[string][]string
+	FacetName string              `json:"facet_name"`
+	FBT       map[string][]string `json:"fbt"`
+	// Multipliers of the number of events of the objects of some categories (and their subcategories),
+	// e.g. to give more signal to the best-selling categories than to the long tail.
+	Multipliers map[string]float64 `json:"multipliers,omitempty"`
+	// Related is the configuration of the related products model, generated if set.
+	Related *Related `json:"related,omitempty"`
+	// Trending is the configuration of the trending items and facets, generated if set.
+	Trending *Trending `json:"trending,omitempty"`
+}
+
+// setDefaults sets the default values of the fields not set.
+func (config *Config) setDefaults() {
+	if config.RecommendFile == "" {
+		config.RecommendFile = DefaultRecommendFile
+	}
+	if config.ClicksPerObject == 0 {
+		config.ClicksPerObject = DefaultClicksPerObject
+	}
+	if config.ConversionsPerObject == 0 {
+		config.ConversionsPerObject = DefaultConversionsPerObject
+	}
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+	if config.SimilarFile == "" {
+		config.SimilarFile = DefaultSimilarFile
+	}
+	if config.FBTFile == "" {
+		config.FBTFile = DefaultFBTFile
+	}
+	if config.RelatedFile == "" {
+		config.RelatedFile = DefaultRelatedFile
+	}
+	if config.TrendingFile == "" {
+		config.TrendingFile = DefaultTrendingFile
+	}
+}
+
+// ParseMultipliers parses a comma separated list of category multipliers, e.g. `Women > Shoes:2,Men:0.5`.
+func ParseMultipliers(spec string) (map[string]float64, error) {
+	return parseValues(spec, "category multiplier", "<category>:<multiplier>")
+}
+
+// ParseAttributes parses a comma separated list of attribute weights, e.g. `brand:3,color:2,price:1`.
+func ParseAttributes(spec string) (map[string]float64, error) {
+	return parseValues(spec, "attribute weight", "<attribute>:<weight>")
+}
+
+// parseValues parses a comma separated list of positive numbers by name, the number after the last colon.
+func parseValues(spec string, what string, format string) (map[string]float64, error) {
+	values := make(map[string]float64)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid %s %q: expected %s", what, item, format)
+		}
+		value, err := strconv.ParseFloat(item[i+1:], 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid %s %q: expected a positive number", what, item)
+		}
+		values[strings.TrimSpace(item[:i])] = value
+	}
+	return values, nil
+}
+
+// Multiplier returns the multiplier of the number of events of a category: the multiplier of the category,
+// or of its closest parent category (`Women > Shoes` for `Women > Shoes > Sneakers`), 1 by default.
+func Multiplier(multipliers map[string]float64, category string) float64 {
+	for {
+		if multiplier, ok := multipliers[category]; ok {
+			return multiplier
+		}
+		i := strings.LastIndex(category, " > ")
+		if i < 0 {
+			return 1
+		}
+		category = category[:i]
+	}
 }
 
 func LoadRecommendConfig(config *Config, filePath string) (*Recommend, error) {
@@ -38,6 +183,10 @@ func LoadRecommendConfig(config *Config, filePath string) (*Recommend, error) {
 		return nil, err
 	}
 
+	bytes, err = renameLegacyKeys(bytes)
+	if err != nil {
+		return nil, err
+	}
 	var recommend Recommend
 	err = json.Unmarshal(bytes, &recommend)
 	if err != nil {
@@ -46,33 +195,170 @@ func LoadRecommendConfig(config *Config, filePath string) (*Recommend, error) {
 	return &recommend, nil
 }
 
+// legacyKeys are the former camelCase keys of the recommend file, still read for the existing files.
+// The keys of the related and trending sections are renamed, not the categories or attributes.
+var legacyKeys = map[string]string{
+	"facetName":         "facet_name",
+	"FBT":               "fbt",
+	"sessionsPerObject": "sessions_per_object",
+	"clicksPerSession":  "clicks_per_session",
+	"conversionRate":    "conversion_rate",
+	"randomClickRate":   "random_click_rate",
+	"objectIDs":         "object_ids",
+}
+
+// renameLegacyKeys renames the legacy keys of a recommend file to the snake_case keys of the scenario.
+func renameLegacyKeys(data []byte) ([]byte, error) {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	rename := func(object map[string]json.RawMessage) {
+		for key, value := range object {
+			if newKey, ok := legacyKeys[key]; ok {
+				if _, exists := object[newKey]; !exists {
+					object[newKey] = value
+				}
+				delete(object, key)
+			}
+		}
+	}
+	rename(root)
+	for _, section := range []string{"related", "trending"} {
+		var object map[string]json.RawMessage
+		if value, ok := root[section]; !ok || json.Unmarshal(value, &object) != nil || object == nil {
+			continue
+		}
+		rename(object)
+		value, err := json.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		root[section] = value
+	}
+	return json.Marshal(root)
+}
+
 // randomDate returns a random date between min and max
-func randomDate(start, end time.Time) time.Time {
-	return time.Unix(rand.Int63n(end.Unix()-start.Unix())+start.Unix(), 0)
+func randomDate(r *rand.Rand, start, end time.Time) time.Time {
+	return time.Unix(r.Int63n(end.Unix()-start.Unix())+start.Unix(), 0)
+}
+
+// newUUID returns a random UUID drawn from r.
+func newUUID(r *rand.Rand) string {
+	id, err := uuid.NewRandomFromReader(r)
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
 }
 
-func LoadRecords(cfg *Config) (*dataframe.DataFrame, error) {
-	res, err := cfg.SearchIndex.BrowseObjects()
+// LoadRecords browses the records of the index, with only their objectID and the given attributes.
+// The facet of the categories is flattened to the deepest category (see flattenCategories).
+func LoadRecords(cfg *Config, facetName string, attributes []string) ([]map[string]interface{}, error) {
+	res, err := cfg.SearchIndex.BrowseObjects(opt.AttributesToRetrieve(attributes...))
 	if err != nil {
 		return nil, err
 	}
-	items := make([]map[string]interface{}, 0)
+	records := make([]map[string]interface{}, 0)
 	for {
 		obj, err := res.Next()
-		if err != nil {
+		if err == io.EOF {
 			break
 		}
-		objM := obj.(map[string]interface{})
-		objM["category_page_id"] = objM["category_page_id"].([]interface{})[len(objM["category_page_id"].([]interface{}))-1]
-		items = append(items, obj.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		if record, ok := obj.(map[string]interface{}); ok {
+			records = append(records, record)
+		}
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records in %s", cfg.SearchIndex.GetName())
+	}
+
+	records, skipped := flattenCategories(records, facetName)
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records with a %q category in %s: check the facet_name of the recommend configuration or the --facet flag",
+			facetName, cfg.SearchIndex.GetName())
+	}
+	if skipped > 0 && cfg.IO != nil {
+		fmt.Fprintf(cfg.IO.ErrOut, "%s %d records without a %q category are ignored\n",
+			cfg.IO.ColorScheme().WarningIcon(), skipped, facetName)
 	}
-	df := dataframe.LoadMaps(items)
-	return &df, nil
+	return records, nil
 }
 
-func GetRandomObjectIDFromCategory(df *dataframe.DataFrame, r *Recommend, facetName string, category string) string {
+// retrievedAttributes returns the top-level attributes of the records the models need: the objectID,
+// the facet of the categories and the attributes of the related products and trending models.
+func retrievedAttributes(facetName string, related *Related, trending *Trending) []string {
+	attributes := []string{"objectID"}
+	seen := map[string]bool{"objectID": true}
+	add := func(attribute string) {
+		attribute = strings.SplitN(attribute, ".", 2)[0]
+		if !seen[attribute] {
+			seen[attribute] = true
+			attributes = append(attributes, attribute)
+		}
+	}
+	add(facetName)
+	if related != nil {
+		for attribute := range related.Attributes {
+			add(attribute)
+		}
+	}
+	if trending != nil {
+		for facet := range trending.Facets {
+			add(facet)
+		}
+	}
+	sort.Strings(attributes[1:])
+	return attributes
+}
+
+// flattenCategories sets the facet of the categories of the records to their deepest category, and
+// returns the records with a category and the number of records without one. The facet may be a string,
+// a list of categories (`["Women", "Women > Shoes"]`), a hierarchical object (`{"lvl0": "Women",
+// "lvl1": "Women > Shoes"}`) or a nested attribute (`hierarchicalCategories.lvl1`).
+func flattenCategories(records []map[string]interface{}, facetName string) ([]map[string]interface{}, int) {
+	items := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		category, depth := "", -1
+		for _, value := range events.AttributeValues(record, facetName) {
+			// The last of the deepest categories, the levels of a hierarchy being separated by " > ".
+			if d := strings.Count(value, " > "); value != "" && d >= depth {
+				category, depth = value, d
+			}
+		}
+		if category == "" {
+			continue
+		}
+		record[facetName] = category
+		items = append(items, record)
+	}
+	return items, len(records) - len(items)
+}
+
+// checkAttributes returns an error if an attribute is missing from all the records.
+func checkAttributes(records []map[string]interface{}, attributes []string, what string) error {
+	for _, attribute := range attributes {
+		found := false
+		for _, record := range records {
+			if len(events.AttributeValues(record, attribute)) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: no records with a %q attribute", what, attribute)
+		}
+	}
+	return nil
+}
+
+func GetRandomObjectIDFromCategory(rnd *rand.Rand, df *dataframe.DataFrame, facetName string, category string) string {
 	dfFiltered := df.Filter(dataframe.F{
-		Colname:    r.FacetName,
+		Colname:    facetName,
 		Comparator: "==",
 		Comparando: category,
 	})
@@ -80,60 +366,140 @@ func GetRandomObjectIDFromCategory(df *dataframe.DataFrame, r *Recommend, facetN
 	if len(objectIDs) == 0 {
 		return ""
 	}
-	return objectIDs[rand.Intn(len(objectIDs))]
+	return objectIDs[rnd.Intn(len(objectIDs))]
 }
 
-func Run(config *Config) error {
-	r, error := LoadRecommendConfig(config, "recommend.json")
-	if error != nil {
-		return error
+// Run generates the events of the similar items, frequently bought together, related products and
+// trending models, and returns where they went.
+func Run(config *Config) ([]*Output, error) {
+	if config.Rand == nil {
+		config.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	config.setDefaults()
+
+	r := config.Recommend
+	if r == nil {
+		var err error
+		r, err = LoadRecommendConfig(config, config.RecommendFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	facetName := r.FacetName
+	if config.FacetName != "" {
+		facetName = config.FacetName
+	}
+	multipliers := make(map[string]float64)
+	for category, multiplier := range r.Multipliers {
+		multipliers[category] = multiplier
+	}
+	for category, multiplier := range config.Multipliers {
+		multipliers[category] = multiplier
+	}
+
+	if facetName == "" {
+		return nil, fmt.Errorf("missing facet of the categories: set the facet_name of the recommend configuration or the --facet flag")
+	}
+
+	related := r.Related
+	if len(config.RelatedAttributes) > 0 {
+		// The flag replaces the attributes of a copy, the recommend configuration is left untouched.
+		withAttributes := Related{}
+		if related != nil {
+			withAttributes = *related
+		}
+		withAttributes.Attributes = config.RelatedAttributes
+		related = &withAttributes
+	}
+	if related != nil && len(related.Attributes) == 0 {
+		related = nil
+	}
+	trending := r.Trending
+	if config.Trending != nil {
+		trending = config.Trending
+	}
+	if trending.IsEmpty() {
+		trending = nil
+	}
+
+	records, err := LoadRecords(config, facetName, retrievedAttributes(facetName, related, trending))
+	if err != nil {
+		return nil, err
+	}
+	if related != nil {
+		attributes := make([]string, 0, len(related.Attributes))
+		for attribute := range related.Attributes {
+			attributes = append(attributes, attribute)
+		}
+		sort.Strings(attributes)
+		if err := checkAttributes(records, attributes, "related products"); err != nil {
+			return nil, err
+		}
+	}
+	if trending != nil {
+		facets := make([]string, 0, len(trending.Facets))
+		for facet := range trending.Facets {
+			facets = append(facets, facet)
+		}
+		sort.Strings(facets)
+		if err := checkAttributes(records, facets, "trending"); err != nil {
+			return nil, err
+		}
 	}
 
-	df, error := LoadRecords(config)
-	if error != nil {
-		return error
+	// The dataframe only holds the objectIDs and categories, as strings.
+	categories := make([]map[string]interface{}, len(records))
+	for i, record := range records {
+		categories[i] = map[string]interface{}{"objectID": fmt.Sprint(record["objectID"]), facetName: record[facetName]}
 	}
+	df := dataframe.LoadMaps(categories, dataframe.DetectTypes(false), dataframe.DefaultType(series.String))
 
 	similarClicks := make(map[string][]string)
-	for _, category := range df.Col(r.FacetName).Records() {
-		// fmt.Println(category)
+	for _, category := range df.Col(facetName).Records() {
 		// Similar items clicks (from the same category)
-		similarClicks[category] = append(similarClicks[category], uuid.NewString())
+		similarClicks[category] = append(similarClicks[category], newUUID(config.Rand))
 	}
 
 	clicksList := make([]insights.Event, 0)
 	conversionsList := make([]insights.Event, 0)
-	daysAgo := time.Now().Add(-time.Hour * 24 * 90)
+	now := time.Now()
+	windowStart := now.Add(-config.Window)
 
 	for _, item := range df.Maps() {
-		category := item[r.FacetName].(string)
-		// 15 clicks per objectID
-		for i := 0; i < 15; i++ {
-			clickUUID := similarClicks[category][rand.Intn(len(similarClicks[category]))]
+		category := item[facetName].(string)
+		multiplier := Multiplier(multipliers, category)
+		clicks := int(math.Round(float64(config.ClicksPerObject) * multiplier))
+		conversions := int(math.Round(float64(config.ConversionsPerObject) * multiplier))
+
+		// Clicks on the object by the users of its category
+		for i := 0; i < clicks; i++ {
+			clickUUID := similarClicks[category][config.Rand.Intn(len(similarClicks[category]))]
 			clicksList = append(clicksList, insights.Event{
 				UserToken: clickUUID,
 				Index:     config.SearchIndex.GetName(),
 				ObjectIDs: []string{item["objectID"].(string)},
-				Timestamp: randomDate(daysAgo, time.Now()),
+				Timestamp: randomDate(config.Rand, windowStart, now),
 				EventType: "click",
 				EventName: "click",
 			})
 		}
 
-		for i := 0; i < 50; i++ {
-			conversionUUID := uuid.NewString()
+		for i := 0; i < conversions; i++ {
+			conversionUUID := newUUID(config.Rand)
+			// The items are bought together: the conversions of the user share the same timestamp.
+			timestamp := randomDate(config.Rand, windowStart, now)
 			// Main conversion
 			conversionsList = append(conversionsList, insights.Event{
 				UserToken: conversionUUID,
 				Index:     config.SearchIndex.GetName(),
 				ObjectIDs: []string{item["objectID"].(string)},
-				Timestamp: randomDate(daysAgo, time.Now()),
+				Timestamp: timestamp,
 				EventType: "conversion",
 				EventName: "conversion",
 			})
 			// FBT conversion, one objectID per FBT category
 			for _, FBTCat := range r.FBT[category] {
-				objectID := GetRandomObjectIDFromCategory(df, r, r.FacetName, FBTCat)
+				objectID := GetRandomObjectIDFromCategory(config.Rand, &df, facetName, FBTCat)
 				if objectID == "" {
 					continue
 				}
@@ -141,7 +507,7 @@ func Run(config *Config) error {
 					UserToken: conversionUUID,
 					Index:     config.SearchIndex.GetName(),
 					ObjectIDs: []string{objectID},
-					Timestamp: randomDate(daysAgo, time.Now()),
+					Timestamp: timestamp,
 					EventType: "conversion",
 					EventName: "conversion",
 				})
@@ -149,43 +515,69 @@ func Run(config *Config) error {
 		}
 	}
 
-	file, err := os.OpenFile("./events-similar.csv", os.O_RDWR|os.O_CREATE, 0755)
-	if err != nil {
-		return err
+	outputs := []*Output{
+		{Model: "Similar items", File: config.SimilarFile},
+		{Model: "Frequently bought together", File: config.FBTFile},
 	}
-	defer file.Close()
+	lists := [][]insights.Event{clicksList, conversionsList}
 
-	csvWriter := csv.NewWriter(file)
-	csvWriter.Write([]string{"userToken", "timestamp", "objectID", "eventType", "eventName"})
-	for _, event := range clicksList {
-		csvWriter.Write([]string{
-			event.UserToken,
-			event.Timestamp.Format("2006-01-02T15:04:05Z"),
-			event.ObjectIDs[0],
-			event.EventType,
-			event.EventName,
-		})
+	// Related products: sessions on similar items, by their attributes.
+	if related != nil {
+		outputs = append(outputs, &Output{Model: "Related products", File: config.RelatedFile})
+		lists = append(lists, RelatedEvents(config.Rand, records, related, facetName, multipliers,
+			config.SearchIndex.GetName(), windowStart, now))
 	}
-	csvWriter.Flush()
 
-	file, err = os.OpenFile("./events-fbt.csv", os.O_RDWR|os.O_CREATE, 0755)
-	if err != nil {
-		return err
+	// Trending items and facets: rising conversions over the last days, above the flat baseline of the window.
+	if trending != nil {
+		dailyConversions := float64(config.ConversionsPerObject) / (config.Window.Hours() / 24)
+		trendingList, err := TrendingEvents(config.Rand, records, trending, facetName, multipliers,
+			dailyConversions, config.SearchIndex.GetName(), now)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, &Output{Model: "Trending", File: config.TrendingFile})
+		lists = append(lists, trendingList)
 	}
-	defer file.Close()
 
-	csvWriter = csv.NewWriter(file)
-	csvWriter.Write([]string{"userToken", "timestamp", "objectID", "eventType", "eventName"})
-	for _, event := range conversionsList {
-		csvWriter.Write([]string{
-			event.UserToken,
-			event.Timestamp.Format("2006-01-02T15:04:05Z"),
-			event.ObjectIDs[0],
-			event.EventType,
-			event.EventName,
-		})
+	for i, list := range lists {
+		if err := writeEvents(config, outputs[i], list); err != nil {
+			return nil, err
+		}
 	}
-	csvWriter.Flush()
+	return outputs, nil
+}
 
-	return nil
+// writeEvents writes the events of a model to its CSV file, replacing its content.
+// When the events are sent, only the ones too old for the Insights API are written to the file,
+// once the others are sent: a failed send leaves the file of the previous run untouched.
+func writeEvents(config *Config, output *Output, list []insights.Event) error {
+	if config.Send {
+		var recent, tooOld []insights.Event
+		for _, event := range list {
+			if events.IsTooOld(event.Timestamp) {
+				tooOld = append(tooOld, event)
+			} else {
+				recent = append(recent, event)
+			}
+		}
+		if err := events.SendEvents(config.InsightsClient, recent); err != nil {
+			return err
+		}
+		output.Sent = len(recent)
+		list = tooOld
+	}
+
+	csvSink, err := events.NewCSVFileSink(output.File)
+	if err != nil {
+		return err
+	}
+	err = csvSink.Write(list)
+	if closeErr := csvSink.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		output.Written = len(list)
+	}
+	return err
 }
@@ -0,0 +1,201 @@
+package recommend
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+func TestParseMultipliers(t *testing.T) {
+	multipliers, err := ParseMultipliers("Women > Shoes:2, Men:0.5,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]float64{"Women > Shoes": 2, "Men": 0.5}
+	if !reflect.DeepEqual(multipliers, expected) {
+		t.Errorf("ParseMultipliers() = %v, want %v", multipliers, expected)
+	}
+
+	for _, spec := range []string{"Women", "Women:many", "Women:-1"} {
+		if _, err := ParseMultipliers(spec); err == nil {
+			t.Errorf("ParseMultipliers(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestMultiplier(t *testing.T) {
+	multipliers := map[string]float64{"Women > Shoes": 2, "Women": 1.5, "Men > Bags": 0}
+	tests := []struct {
+		category string
+		expected float64
+	}{
+		{"Women > Shoes", 2},
+		{"Women > Shoes > Sneakers", 2},
+		{"Women > Clothing", 1.5},
+		{"Men > Bags", 0},
+		{"Men > Shoes", 1},
+		{"Kids", 1},
+	}
+	for _, tt := range tests {
+		if got := Multiplier(multipliers, tt.category); got != tt.expected {
+			t.Errorf("Multiplier(%q) = %f, want %f", tt.category, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteEvents_Truncate(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "events.csv")
+	event := insights.Event{UserToken: "user", ObjectIDs: []string{"1"}, Timestamp: time.Now(), EventType: "click", EventName: "click"}
+
+	// A shorter rerun replaces the rows of the previous run.
+	if err := writeEvents(&Config{}, &Output{File: fileName}, []insights.Event{event, event, event}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := &Output{File: fileName}
+	if err := writeEvents(&Config{}, output, []insights.Event{event}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Written != 1 || output.Sent != 0 {
+		t.Errorf("expected 1 event written and none sent, got %+v", output)
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("expected a header and 1 row, got %d lines:\n%s", lines, data)
+	}
+}
+
+// failingRequester fails every request to the Insights API.
+type failingRequester struct{}
+
+func (failingRequester) Request(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("network unreachable")
+}
+
+func TestWriteEvents_FailedSend(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "events.csv")
+	if err := ioutil.WriteFile(fileName, []byte("previous run\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := insights.NewClientWithConfig(insights.Configuration{
+		AppID: "app", APIKey: "key", Hosts: []string{"insights.invalid"}, Requester: failingRequester{},
+	})
+	recent := insights.Event{UserToken: "user", ObjectIDs: []string{"1"}, Timestamp: time.Now(), EventType: "click", EventName: "click"}
+	old := recent
+	old.Timestamp = time.Now().Add(-30 * 24 * time.Hour)
+
+	// The file of the previous run is kept when the send fails.
+	output := &Output{File: fileName}
+	if err := writeEvents(&Config{Send: true, InsightsClient: client}, output, []insights.Event{recent, old}); err == nil {
+		t.Fatalf("expected a send error")
+	}
+	if output.Written != 0 || output.Sent != 0 {
+		t.Errorf("expected nothing written nor sent, got %+v", output)
+	}
+	if data, _ := ioutil.ReadFile(fileName); string(data) != "previous run\n" {
+		t.Errorf("expected the file of the previous run to be kept, got:\n%s", data)
+	}
+}
+
+func TestFlattenCategories(t *testing.T) {
+	records := []map[string]interface{}{
+		{"objectID": "1", "category": "Books"},
+		{"objectID": "2", "category": []interface{}{"Women", "Women > Shoes", "Women > Shoes > Sneakers"}},
+		{"objectID": "3", "category": map[string]interface{}{"lvl0": "Men", "lvl1": "Men > Bags"}},
+		{"objectID": "4", "category": map[string]interface{}{"lvl0": []interface{}{"Men", "Kids"}, "lvl1": []interface{}{"Men > Shoes", "Kids > Shoes"}}},
+		{"objectID": "5", "category": 42.0},
+		{"objectID": "6", "category": []interface{}{}},
+		{"objectID": "7", "category": ""},
+		{"objectID": "8"},
+	}
+	items, skipped := flattenCategories(records, "category")
+	expected := map[string]string{"1": "Books", "2": "Women > Shoes > Sneakers", "3": "Men > Bags", "4": "Kids > Shoes", "5": "42"}
+	if len(items) != len(expected) || skipped != 3 {
+		t.Fatalf("got %d records and %d skipped, want %d and 3", len(items), skipped, len(expected))
+	}
+	for _, item := range items {
+		if item["category"] != expected[item["objectID"].(string)] {
+			t.Errorf("record %s: got category %v, want %q", item["objectID"], item["category"], expected[item["objectID"].(string)])
+		}
+	}
+
+	// A level of a hierarchical object.
+	records = []map[string]interface{}{
+		{"objectID": "1", "hierarchicalCategories": map[string]interface{}{"lvl0": "Men", "lvl1": "Men > Bags"}},
+	}
+	items, _ = flattenCategories(records, "hierarchicalCategories.lvl0")
+	if len(items) != 1 || items[0]["hierarchicalCategories.lvl0"] != "Men" {
+		t.Errorf("unexpected records: %v", items)
+	}
+}
+
+func TestRetrievedAttributes(t *testing.T) {
+	related := &Related{Attributes: map[string]float64{"brand": 3, "price": 1}}
+	trending := &Trending{Facets: map[string][]string{"brand": {"Nike"}, "color": {"red"}}}
+	tests := []struct {
+		name     string
+		related  *Related
+		trending *Trending
+		expected []string
+	}{
+		{"categories only", nil, nil, []string{"objectID", "hierarchicalCategories"}},
+		{"related and trending", related, trending, []string{"objectID", "brand", "color", "hierarchicalCategories", "price"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retrievedAttributes("hierarchicalCategories.lvl1", tt.related, tt.trending)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("retrievedAttributes() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckAttributes(t *testing.T) {
+	records := loadTestRecords(t)
+	if err := checkAttributes(records, []string{"brand", "price"}, "related products"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := checkAttributes(records, []string{"brand", "size"}, "related products")
+	if err == nil || err.Error() != `related products: no records with a "size" attribute` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadRecommendConfig_LegacyKeys(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "recommend.json")
+	data := `{
+		"facetName": "category_page_id",
+		"FBT": {"Women > Shoes": ["Women > Bags"]},
+		"multipliers": {"facetName": 2},
+		"related": {"attributes": {"conversionRate": 1}, "sessionsPerObject": 3, "randomClickRate": 5},
+		"trending": {"objectIDs": ["1"], "days": 3}
+	}`
+	if err := ioutil.WriteFile(fileName, []byte(data), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := LoadRecommendConfig(nil, fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &Recommend{
+		FacetName:   "category_page_id",
+		FBT:         map[string][]string{"Women > Shoes": {"Women > Bags"}},
+		Multipliers: map[string]float64{"facetName": 2},
+		Related:     &Related{Attributes: map[string]float64{"conversionRate": 1}, SessionsPerObject: 3, RandomClickRate: floatPtr(5)},
+		Trending:    &Trending{ObjectIDs: []string{"1"}, Days: 3},
+	}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("LoadRecommendConfig() = %+v, want %+v", r, expected)
+	}
+}
@@ -0,0 +1,406 @@
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+)
+
+// Defaults of the related products model.
+const (
+	DefaultRelatedFile            = "events-related.csv"
+	defaultSessionsPerObject      = 5
+	defaultClicksPerSession       = 4
+	defaultRelatedConversionRate  = 20
+	defaultNeighbors              = 10
+	defaultRelatedRandomClickRate = 10
+
+	// maxNeighborCandidates is the maximum number of items compared with each item to find its
+	// neighbors: above it, a random sample of the candidates is compared.
+	maxNeighborCandidates = 1000
+)
+
+// Related is the configuration of the related products model: the users of a session click,
+// and eventually convert, mostly on items similar to the first one they clicked.
+type Related struct {
+	// Attributes are the attributes defining the similarity of two items, with their weights,
+	// e.g. {"brand": 3, "color": 2, "price": 1}. The strings are compared for equality, the lists
+	// by their common values and the numbers by their relative difference (e.g. a price band). The
+	// attributes can be nested, e.g. brand.name.
+	Attributes map[string]float64 `json:"attributes"`
+
+	// SessionsPerObject is the number of sessions starting on each item.
+	SessionsPerObject int `json:"sessions_per_object,omitempty"`
+	// ClicksPerSession is the number of clicks of a session, the first item included.
+	ClicksPerSession int `json:"clicks_per_session,omitempty"`
+	// ConversionRate is the percentage of sessions with a conversion on one of the clicked items.
+	// The rates are pointers, so they can be set to 0: nil is the default rate.
+	ConversionRate *float64 `json:"conversion_rate,omitempty"`
+	// Neighbors is the number of most similar items the users click on.
+	Neighbors int `json:"neighbors,omitempty"`
+	// RandomClickRate is the percentage of clicks on a random item instead of a similar one.
+	RandomClickRate *float64 `json:"random_click_rate,omitempty"`
+}
+
+// withDefaults returns a copy of the configuration, with the default values of the fields not set.
+func (related Related) withDefaults() Related {
+	if related.SessionsPerObject == 0 {
+		related.SessionsPerObject = defaultSessionsPerObject
+	}
+	if related.ClicksPerSession == 0 {
+		related.ClicksPerSession = defaultClicksPerSession
+	}
+	if related.ConversionRate == nil {
+		related.ConversionRate = floatPtr(defaultRelatedConversionRate)
+	}
+	if related.Neighbors == 0 {
+		related.Neighbors = defaultNeighbors
+	}
+	if related.RandomClickRate == nil {
+		related.RandomClickRate = floatPtr(defaultRelatedRandomClickRate)
+	}
+	return related
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+// neighbor is a similar item, with its similarity in [0, 1].
+type neighbor struct {
+	index      int
+	similarity float64
+}
+
+// Similarity returns the similarity of two records, in [0, 1]: the weighted average of the similarity
+// of their attributes. A missing attribute is not similar.
+func Similarity(attributes map[string]float64, a, b map[string]interface{}) float64 {
+	return similarity(sortedWeights(attributes), a, b)
+}
+
+// weight is the weight of a related attribute.
+type weight struct {
+	attribute string
+	weight    float64
+}
+
+// sortedWeights returns the weights of the attributes, sorted by attribute name so that the sums
+// of floats, and so the neighbors, don't depend on the map iteration order.
+func sortedWeights(attributes map[string]float64) []weight {
+	weights := make([]weight, 0, len(attributes))
+	for attribute, w := range attributes {
+		weights = append(weights, weight{attribute: attribute, weight: w})
+	}
+	sort.Slice(weights, func(i, j int) bool { return weights[i].attribute < weights[j].attribute })
+	return weights
+}
+
+func similarity(weights []weight, a, b map[string]interface{}) float64 {
+	total, similarity := 0.0, 0.0
+	for _, w := range weights {
+		total += w.weight
+		similarity += w.weight * valueSimilarity(attributeValue(a, w.attribute), attributeValue(b, w.attribute))
+	}
+	if total == 0 {
+		return 0
+	}
+	return similarity / total
+}
+
+// attributeValue returns the value of an attribute of a record, which can be nested (e.g. brand.name),
+// including in a list of objects (e.g. variants.color, the list of the colors of the variants). Like
+// events.AttributeValues, but the values keep their types so the numbers are still compared by their
+// relative difference.
+func attributeValue(record map[string]interface{}, attribute string) interface{} {
+	var value interface{} = record
+	for _, key := range strings.Split(attribute, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			values := make([]interface{}, 0, len(v))
+			for _, e := range v {
+				m, ok := e.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				switch nested := m[key].(type) {
+				case nil:
+				case []interface{}:
+					values = append(values, nested...)
+				default:
+					values = append(values, nested)
+				}
+			}
+			value = values
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+func valueSimilarity(a, b interface{}) float64 {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok && strings.EqualFold(a, b) {
+			return 1
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			if a == b {
+				return 1
+			}
+			return math.Max(0, 1-math.Abs(a-b)/math.Max(math.Abs(a), math.Abs(b)))
+		}
+	case bool:
+		if b, ok := b.(bool); ok && a == b {
+			return 1
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok {
+			return jaccard(a, b)
+		}
+	}
+	return 0
+}
+
+// jaccard returns the number of common values of two lists, over the number of distinct values.
+func jaccard(a, b []interface{}) float64 {
+	set := make(map[string]bool)
+	for _, v := range a {
+		set[strings.ToLower(fmt.Sprint(v))] = true
+	}
+	common, union := 0, len(set)
+	seen := make(map[string]bool)
+	for _, v := range b {
+		key := strings.ToLower(fmt.Sprint(v))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if set[key] {
+			common++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// neighbors returns the most similar items of each record, the most similar first. Comparing every
+// pair of items doesn't scale with the catalog, so each item is only compared with the items of its
+// category and the items sharing a value of its highest-weight non-numeric attribute, and with a random
+// sample of maxCandidates of them when there are more.
+func neighbors(r *rand.Rand, records []map[string]interface{}, attributes map[string]float64, facetName string,
+	count, maxCandidates int) [][]neighbor {
+	weights := sortedWeights(attributes)
+	top := bucketAttribute(records, weights)
+
+	buckets := make(map[string][]int)
+	keys := make([][]string, len(records))
+	for i, record := range records {
+		keys[i] = bucketKeys(record, facetName, top)
+		for _, key := range keys[i] {
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	all := make([][]neighbor, len(records))
+	for i := range records {
+		lists := make([][]int, len(keys[i]))
+		for k, key := range keys[i] {
+			lists[k] = buckets[key]
+		}
+		indexes := sampleCandidates(r, i, lists, maxCandidates)
+
+		candidates := make([]neighbor, 0)
+		for _, j := range indexes {
+			if similarity := similarity(weights, records[i], records[j]); similarity > 0 {
+				candidates = append(candidates, neighbor{index: j, similarity: similarity})
+			}
+		}
+		sort.Slice(candidates, func(a, b int) bool {
+			if candidates[a].similarity != candidates[b].similarity {
+				return candidates[a].similarity > candidates[b].similarity
+			}
+			return candidates[a].index < candidates[b].index
+		})
+		if len(candidates) > count {
+			candidates = candidates[:count]
+		}
+		all[i] = candidates
+	}
+	return all
+}
+
+// sampleCandidates returns the distinct items of the buckets of an item, the item excluded, in
+// ascending order. When the buckets hold more than maxCandidates items, random positions of the
+// buckets are drawn instead, so the cost doesn't depend on the size of the buckets: the sample
+// can then be smaller than maxCandidates when the buckets overlap.
+func sampleCandidates(r *rand.Rand, item int, buckets [][]int, maxCandidates int) []int {
+	total := 0
+	for _, bucket := range buckets {
+		total += len(bucket)
+	}
+
+	seen := map[int]bool{item: true}
+	indexes := make([]int, 0)
+	add := func(j int) {
+		if !seen[j] {
+			seen[j] = true
+			indexes = append(indexes, j)
+		}
+	}
+	if total-len(buckets) <= maxCandidates {
+		for _, bucket := range buckets {
+			for _, j := range bucket {
+				add(j)
+			}
+		}
+	} else {
+		for draws := 0; draws < 4*maxCandidates && len(indexes) < maxCandidates; draws++ {
+			position := r.Intn(total)
+			for _, bucket := range buckets {
+				if position < len(bucket) {
+					add(bucket[position])
+					break
+				}
+				position -= len(bucket)
+			}
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// bucketAttribute returns the highest-weight attribute whose values are not numbers, the numbers
+// being similar by their relative difference rather than by their equality. It returns an empty
+// string when all the attributes are numeric.
+func bucketAttribute(records []map[string]interface{}, weights []weight) string {
+	byWeight := append([]weight(nil), weights...)
+	sort.SliceStable(byWeight, func(i, j int) bool { return byWeight[i].weight > byWeight[j].weight })
+	for _, w := range byWeight {
+		if !isNumeric(records, w.attribute) {
+			return w.attribute
+		}
+	}
+	return ""
+}
+
+// isNumeric returns true if the first value of the attribute found in the records is a number.
+func isNumeric(records []map[string]interface{}, attribute string) bool {
+	for _, record := range records {
+		value := attributeValue(record, attribute)
+		if values, ok := value.([]interface{}); ok {
+			if len(values) == 0 {
+				continue
+			}
+			value = values[0]
+		}
+		if value != nil {
+			_, ok := value.(float64)
+			return ok
+		}
+	}
+	return false
+}
+
+// bucketKeys returns the buckets of a record: its category and the values of the attribute, if any.
+func bucketKeys(record map[string]interface{}, facetName, attribute string) []string {
+	keys := make([]string, 0)
+	if category, ok := record[facetName].(string); ok {
+		keys = append(keys, "category:"+category)
+	}
+	if attribute == "" {
+		return keys
+	}
+	value := attributeValue(record, attribute)
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	for _, value := range values {
+		if value != nil {
+			keys = append(keys, "attribute:"+strings.ToLower(fmt.Sprint(value)))
+		}
+	}
+	return keys
+}
+
+// pickNeighbor picks one of the neighbors, weighted by their similarity.
+func pickNeighbor(r *rand.Rand, candidates []neighbor) int {
+	total := 0.0
+	for _, n := range candidates {
+		total += n.similarity
+	}
+	pick := r.Float64() * total
+	for _, n := range candidates {
+		pick -= n.similarity
+		if pick < 0 {
+			return n.index
+		}
+	}
+	return candidates[len(candidates)-1].index
+}
+
+// RelatedEvents generates the sessions of the related products model, spread between start and end:
+// each session clicks an item, then mostly similar items, and eventually converts on one of them.
+// The number of sessions of an item is multiplied by the multiplier of its category.
+func RelatedEvents(r *rand.Rand, records []map[string]interface{}, related *Related, facetName string,
+	multipliers map[string]float64, indexName string, start, end time.Time) []insights.Event {
+	cfg := related.withDefaults()
+	similar := neighbors(r, records, cfg.Attributes, facetName, cfg.Neighbors, maxNeighborCandidates)
+
+	// The sessions last a few minutes: they start an hour before the end at the latest.
+	if end.Sub(start) > 2*time.Hour {
+		end = end.Add(-time.Hour)
+	}
+
+	list := make([]insights.Event, 0)
+	newEvent := func(userToken string, record map[string]interface{}, eventType string, timestamp time.Time) insights.Event {
+		return insights.Event{
+			UserToken: userToken,
+			Index:     indexName,
+			ObjectIDs: []string{fmt.Sprint(record["objectID"])},
+			Timestamp: timestamp,
+			EventType: eventType,
+			EventName: eventType,
+		}
+	}
+	for i, record := range records {
+		category, _ := record[facetName].(string)
+		sessions := int(math.Round(float64(cfg.SessionsPerObject) * Multiplier(multipliers, category)))
+		for s := 0; s < sessions; s++ {
+			userToken := newUUID(r)
+			timestamp := randomDate(r, start, end)
+			clicked := []int{i}
+			list = append(list, newEvent(userToken, record, "click", timestamp))
+
+			for c := 1; c < cfg.ClicksPerSession; c++ {
+				next := r.Intn(len(records))
+				if len(similar[i]) > 0 && r.Float64() >= *cfg.RandomClickRate/100 {
+					next = pickNeighbor(r, similar[i])
+				}
+				clicked = append(clicked, next)
+				timestamp = timestamp.Add(10*time.Second + time.Duration(r.Int63n(int64(2*time.Minute))))
+				list = append(list, newEvent(userToken, records[next], "click", timestamp))
+			}
+
+			if r.Float64() < *cfg.ConversionRate/100 {
+				converted := clicked[r.Intn(len(clicked))]
+				timestamp = timestamp.Add(time.Minute + time.Duration(r.Int63n(int64(4*time.Minute))))
+				list = append(list, newEvent(userToken, records[converted], "conversion", timestamp))
+			}
+		}
+	}
+	return list
+}
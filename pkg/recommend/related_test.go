@@ -0,0 +1,224 @@
+package recommend
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var relatedAttributes = map[string]float64{"brand": 3, "color": 2, "price": 1, "categories": 1}
+
+// loadTestRecords loads the testdata records, with their category flattened like LoadRecords.
+func loadTestRecords(t *testing.T) []map[string]interface{} {
+	data, err := ioutil.ReadFile("testdata/records.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, _ = flattenCategories(records, "category_page_id")
+	return records
+}
+
+func TestSimilarity(t *testing.T) {
+	records := loadTestRecords(t)
+	tests := []struct {
+		name     string
+		a, b     int
+		expected float64
+	}{
+		// Same brand, color (case insensitive) and categories, 10% price difference.
+		{"same brand and color", 0, 1, (3 + 2 + (1 - 10.0/110) + 1) / 7},
+		{"same brand", 0, 2, (3 + 0 + 0.9 + 1) / 7},
+		{"same color", 0, 3, (0 + 2 + 0.95 + 1) / 7},
+		{"same color, other category", 0, 5, (0 + 2 + 0.85 + 0) / 7},
+		{"nothing in common but the price", 4, 7, (0 + 0 + 1.0/3 + 1.0/3) / 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Similarity(relatedAttributes, records[tt.a], records[tt.b]); math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("Similarity() = %f, want %f", got, tt.expected)
+			}
+		})
+	}
+
+	if got := Similarity(map[string]float64{"material": 1}, records[0], records[1]); got != 0 {
+		t.Errorf("expected no similarity on a missing attribute, got %f", got)
+	}
+}
+
+func TestSimilarity_NestedAttributes(t *testing.T) {
+	var records []map[string]interface{}
+	err := json.Unmarshal([]byte(`[
+		{"brand": {"name": "Nike"}, "price": {"value": 100}, "variants": [{"color": "black"}, {"color": "white"}]},
+		{"brand": {"name": "nike"}, "price": {"value": 110}, "variants": [{"color": "Black"}, {"color": "red"}]},
+		{"brand": "Nike", "variants": "black"}
+	]`), &records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attributes := map[string]float64{"brand.name": 3, "price.value": 1, "variants.color": 2}
+
+	// Same brand, 10% price difference and 1 common color out of 3.
+	expected := (3 + (1 - 10.0/110) + 2.0/3) / 6
+	if got := Similarity(attributes, records[0], records[1]); math.Abs(got-expected) > 1e-9 {
+		t.Errorf("Similarity() = %f, want %f", got, expected)
+	}
+	if got := Similarity(attributes, records[0], records[2]); got != 0 {
+		t.Errorf("expected no similarity on a missing nested attribute, got %f", got)
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	records := loadTestRecords(t)
+	indexes := func(list []neighbor) []int {
+		result := make([]int, 0, len(list))
+		for _, n := range list {
+			result = append(result, n.index)
+		}
+		return result
+	}
+	similar := neighbors(rand.New(rand.NewSource(1)), records, relatedAttributes, "category_page_id", 10, maxNeighborCandidates)
+	tests := []struct {
+		name     string
+		record   int
+		expected []int
+	}{
+		// The other sneakers, but not the Levi's 511, however similar in color and price.
+		{"same category or brand", 0, []int{1, 2, 3}},
+		// The Zara dresses share the blue color, but neither the category nor the brand.
+		{"nothing shared but the color", 4, []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := indexes(similar[tt.record]); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("neighbors() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	sampled := neighbors(rand.New(rand.NewSource(1)), records, relatedAttributes, "category_page_id", 10, 1)
+	if len(sampled[0]) != 1 {
+		t.Errorf("expected a single compared candidate, got %v", indexes(sampled[0]))
+	}
+}
+
+func TestSampleCandidates(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	large := make([]int, 3000)
+	for i := range large {
+		large[i] = i
+	}
+
+	// Overlapping buckets: the candidates are distinct, the item excluded.
+	if got := sampleCandidates(r, 1, [][]int{{0, 1, 2}, {1, 2, 3}}, 10); !reflect.DeepEqual(got, []int{0, 2, 3}) {
+		t.Errorf("sampleCandidates() = %v, want [0 2 3]", got)
+	}
+
+	// Large buckets: a sample of distinct candidates, drawn from the positions of the buckets.
+	got := sampleCandidates(r, 0, [][]int{large, {0, 5}}, 100)
+	if len(got) != 100 {
+		t.Fatalf("expected 100 candidates, got %d", len(got))
+	}
+	for k, j := range got {
+		if j == 0 || (k > 0 && j <= got[k-1]) {
+			t.Fatalf("expected distinct sorted candidates without the item, got %v", got)
+		}
+	}
+}
+
+func TestBucketAttribute(t *testing.T) {
+	records := loadTestRecords(t)
+	tests := []struct {
+		name       string
+		attributes map[string]float64
+		expected   string
+	}{
+		{"highest weight", relatedAttributes, "brand"},
+		// The prices are compared by their relative difference: they are not a bucket.
+		{"numeric highest weight", map[string]float64{"price": 5, "color": 2, "brand": 1}, "color"},
+		{"only numbers", map[string]float64{"price": 1}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bucketAttribute(records, sortedWeights(tt.attributes)); got != tt.expected {
+				t.Errorf("bucketAttribute() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRelatedEvents(t *testing.T) {
+	records := loadTestRecords(t)
+	end := time.Now()
+	start := end.Add(-30 * 24 * time.Hour)
+	related := &Related{Attributes: relatedAttributes, Neighbors: 2}
+	multipliers := map[string]float64{"Clothing > Dresses": 0}
+
+	list := RelatedEvents(rand.New(rand.NewSource(1)), records, related, "category_page_id", multipliers, "products", start, end)
+
+	// 5 sessions of 4 clicks for each of the 6 items not in the dresses category.
+	clicks, conversions := 0, 0
+	sessions := make(map[string][]string)
+	for _, event := range list {
+		if event.Timestamp.Before(start) || event.Timestamp.After(end) {
+			t.Errorf("event out of the window: %s", event.Timestamp)
+		}
+		switch event.EventType {
+		case "click":
+			clicks++
+			sessions[event.UserToken] = append(sessions[event.UserToken], event.ObjectIDs[0])
+		case "conversion":
+			conversions++
+		}
+	}
+	if len(sessions) != 6*5 || clicks != 6*5*4 {
+		t.Errorf("expected 30 sessions and 120 clicks, got %d sessions and %d clicks", len(sessions), clicks)
+	}
+	if conversions == 0 || conversions > 15 {
+		t.Errorf("expected about 20%% of sessions with a conversion, got %d", conversions)
+	}
+
+	// The clicks after the first one are mostly on the 2 most similar items.
+	similar := neighbors(rand.New(rand.NewSource(1)), records, relatedAttributes, "category_page_id", 2, maxNeighborCandidates)
+	byObjectID := make(map[string]int)
+	for i, record := range records {
+		byObjectID[record["objectID"].(string)] = i
+	}
+	onSimilar, following := 0, 0
+	for _, objectIDs := range sessions {
+		first := byObjectID[objectIDs[0]]
+		for _, objectID := range objectIDs[1:] {
+			following++
+			for _, n := range similar[first] {
+				if n.index == byObjectID[objectID] {
+					onSimilar++
+				}
+			}
+		}
+	}
+	if ratio := float64(onSimilar) / float64(following); ratio < 0.8 {
+		t.Errorf("expected most clicks on similar items, got %.0f%%", ratio*100)
+	}
+	if top := similar[0][0].index; top != 1 {
+		t.Errorf("expected the most similar item of the Air Max to be the Air Force, got %v", records[top]["name"])
+	}
+
+	// The rates can be set to 0, and the configuration is left untouched by the defaults.
+	related = &Related{Attributes: relatedAttributes, ConversionRate: floatPtr(0), RandomClickRate: floatPtr(0)}
+	list = RelatedEvents(rand.New(rand.NewSource(1)), records, related, "category_page_id", multipliers, "products", start, end)
+	for _, event := range list {
+		if event.EventType == "conversion" {
+			t.Fatalf("expected no conversions with a conversion rate of 0")
+		}
+	}
+	if related.SessionsPerObject != 0 || related.Neighbors != 0 {
+		t.Errorf("expected the configuration to be left untouched, got %+v", related)
+	}
+}
@@ -0,0 +1,149 @@
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/insights"
+	"github.com/algolia/fake-insights-generator/pkg/events"
+)
+
+// Defaults of the trending model.
+const (
+	DefaultTrendingFile  = "events-trending.csv"
+	defaultTrendingDays  = 7
+	defaultTrendingBoost = 5
+)
+
+// Trending is the configuration of the trending items and trending facets models: the conversions
+// of some items, or of the items with some facet values, rise over the last days.
+type Trending struct {
+	// ObjectIDs are the trending items.
+	ObjectIDs []string `json:"object_ids,omitempty"`
+	// Facets are the trending facet values, by facet name, e.g. {"brand": ["Nike"]}.
+	Facets map[string][]string `json:"facets,omitempty"`
+	// Days is the number of days of the rise, before now.
+	Days int `json:"days,omitempty"`
+	// Boost is the multiplier of the daily conversions of the trending items on the last day,
+	// compared with their flat baseline. The conversions rise linearly up to it.
+	Boost float64 `json:"boost,omitempty"`
+}
+
+// withDefaults returns a copy of the configuration, with the default values of the fields not set.
+func (trending Trending) withDefaults() Trending {
+	if trending.Days == 0 {
+		trending.Days = defaultTrendingDays
+	}
+	if trending.Boost == 0 {
+		trending.Boost = defaultTrendingBoost
+	}
+	return trending
+}
+
+// IsEmpty returns true if nothing trends.
+func (trending *Trending) IsEmpty() bool {
+	return trending == nil || (len(trending.ObjectIDs) == 0 && len(trending.Facets) == 0)
+}
+
+// ParseTrendingFacets parses a comma separated list of trending facet values, e.g. `brand:Nike,color:red`.
+func ParseTrendingFacets(spec string) (map[string][]string, error) {
+	facets := make(map[string][]string)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid trending facet %q: expected <facet>:<value>", item)
+		}
+		facets[parts[0]] = append(facets[parts[0]], parts[1])
+	}
+	return facets, nil
+}
+
+// Matches returns true if a record trends: its objectID or one of its (possibly nested) facet values is trending.
+// The category facet of the records is flattened to their deepest category (see LoadRecords): it matches a trending
+// category or any of its subcategories, like the multipliers.
+func (trending *Trending) Matches(record map[string]interface{}, facetName string) bool {
+	objectID := fmt.Sprint(record["objectID"])
+	for _, id := range trending.ObjectIDs {
+		if id == objectID {
+			return true
+		}
+	}
+	for facet, values := range trending.Facets {
+		for _, recordValue := range events.AttributeValues(record, facet) {
+			for _, value := range values {
+				if strings.EqualFold(recordValue, value) {
+					return true
+				}
+				if facet == facetName && len(recordValue) > len(value)+3 &&
+					strings.EqualFold(recordValue[:len(value)+3], value+" > ") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// TrendingEvents generates the conversions of the trending items on top of their flat baseline
+// (dailyConversions per item, multiplied by the multiplier of their category): on the last day,
+// the conversions are Boost times the baseline, rising linearly from the baseline Days days ago.
+func TrendingEvents(r *rand.Rand, records []map[string]interface{}, trending *Trending, facetName string,
+	multipliers map[string]float64, dailyConversions float64, indexName string, now time.Time) ([]insights.Event, error) {
+	cfg := trending.withDefaults()
+	if cfg.Days < 1 || cfg.Boost < 1 {
+		return nil, fmt.Errorf("trending: the days and the boost must be at least 1")
+	}
+
+	// Every trending objectID must exist.
+	found := make(map[string]bool)
+	for _, record := range records {
+		found[fmt.Sprint(record["objectID"])] = true
+	}
+	for _, objectID := range cfg.ObjectIDs {
+		if !found[objectID] {
+			return nil, fmt.Errorf("trending: unknown objectID %q", objectID)
+		}
+	}
+
+	list := make([]insights.Event, 0)
+	matches := 0
+	for _, record := range records {
+		if !cfg.Matches(record, facetName) {
+			continue
+		}
+		matches++
+		category, _ := record[facetName].(string)
+		baseline := dailyConversions * Multiplier(multipliers, category)
+
+		for day := 1; day <= cfg.Days; day++ {
+			// Extra conversions of the day, the fraction being a probability.
+			extra := baseline * (cfg.Boost - 1) * float64(day) / float64(cfg.Days)
+			count := int(math.Floor(extra))
+			if r.Float64() < extra-math.Floor(extra) {
+				count++
+			}
+			dayStart := now.Add(-time.Duration(cfg.Days-day+1) * 24 * time.Hour)
+			for i := 0; i < count; i++ {
+				list = append(list, insights.Event{
+					UserToken: newUUID(r),
+					Index:     indexName,
+					ObjectIDs: []string{fmt.Sprint(record["objectID"])},
+					Timestamp: randomDate(r, dayStart, dayStart.Add(24*time.Hour)),
+					EventType: "conversion",
+					EventName: "conversion",
+				})
+			}
+		}
+	}
+	if matches == 0 {
+		return nil, fmt.Errorf("trending: no items match the trending facet values")
+	}
+	return list, nil
+}
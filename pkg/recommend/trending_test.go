@@ -0,0 +1,110 @@
+package recommend
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTrendingFacets(t *testing.T) {
+	facets, err := ParseTrendingFacets("brand:Nike, brand:Zara,category:Shoes > Sneakers,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][]string{"brand": {"Nike", "Zara"}, "category": {"Shoes > Sneakers"}}
+	if !reflect.DeepEqual(facets, expected) {
+		t.Errorf("ParseTrendingFacets() = %v, want %v", facets, expected)
+	}
+
+	for _, spec := range []string{"Nike", "brand:", ":Nike"} {
+		if _, err := ParseTrendingFacets(spec); err == nil {
+			t.Errorf("ParseTrendingFacets(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestTrending_Matches(t *testing.T) {
+	records := loadTestRecords(t)
+	tests := []struct {
+		name     string
+		trending Trending
+		expected []string
+	}{
+		{"objectIDs", Trending{ObjectIDs: []string{"2", "7"}}, []string{"2", "7"}},
+		{"facet value, case insensitive", Trending{Facets: map[string][]string{"brand": {"levi's"}}}, []string{"5", "6"}},
+		{"list facet", Trending{Facets: map[string][]string{"categories": {"Clothing > Dresses"}}}, []string{"7", "8"}},
+		{"objectIDs or facet values", Trending{ObjectIDs: []string{"1"}, Facets: map[string][]string{"color": {"red"}}}, []string{"1", "8"}},
+		{"unknown facet", Trending{Facets: map[string][]string{"size": {"M"}}}, []string{}},
+		// The flattened category matches a trending category and its subcategories.
+		{"category", Trending{Facets: map[string][]string{"category_page_id": {"shoes"}}}, []string{"1", "2", "3", "4"}},
+		{"subcategory", Trending{Facets: map[string][]string{"category_page_id": {"Clothing > Jeans"}}}, []string{"5", "6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := []string{}
+			for _, record := range records {
+				if tt.trending.Matches(record, "category_page_id") {
+					matches = append(matches, record["objectID"].(string))
+				}
+			}
+			if !reflect.DeepEqual(matches, tt.expected) {
+				t.Errorf("Matches() = %v, want %v", matches, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrendingEvents(t *testing.T) {
+	records := loadTestRecords(t)
+	now := time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC)
+	trending := &Trending{Facets: map[string][]string{"brand": {"Nike"}}, Days: 4, Boost: 3}
+	multipliers := map[string]float64{"Shoes": 2}
+
+	list, err := TrendingEvents(rand.New(rand.NewSource(1)), records, trending, "category_page_id", multipliers, 10, "index", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 3 Nike items, a baseline of 20 conversions per day: 10, 20, 30 and 40 extra conversions per item.
+	if len(list) != 3*(10+20+30+40) {
+		t.Errorf("got %d events, want %d", len(list), 3*(10+20+30+40))
+	}
+	perDay := make([]int, trending.Days)
+	for _, event := range list {
+		if event.EventType != "conversion" || event.Index != "index" || len(event.ObjectIDs) != 1 {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+		if event.ObjectIDs[0] != "1" && event.ObjectIDs[0] != "2" && event.ObjectIDs[0] != "3" {
+			t.Errorf("unexpected trending object: %s", event.ObjectIDs[0])
+		}
+		age := now.Sub(event.Timestamp)
+		if age < 0 || age >= time.Duration(trending.Days)*24*time.Hour {
+			t.Fatalf("event out of the last %d days: %s", trending.Days, event.Timestamp)
+		}
+		perDay[trending.Days-1-int(age/(24*time.Hour))]++
+	}
+	if !reflect.DeepEqual(perDay, []int{30, 60, 90, 120}) {
+		t.Errorf("got %v conversions per day, want [30 60 90 120]", perDay)
+	}
+
+	tests := []struct {
+		name     string
+		trending *Trending
+		wantErr  string
+	}{
+		{"unknown objectID", &Trending{ObjectIDs: []string{"1", "42"}}, `unknown objectID "42"`},
+		{"no match", &Trending{Facets: map[string][]string{"brand": {"Puma"}}}, "no items match"},
+		{"negative days", &Trending{ObjectIDs: []string{"1"}, Days: -1}, "must be at least 1"},
+		{"boost below 1", &Trending{ObjectIDs: []string{"1"}, Boost: 0.5}, "must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := TrendingEvents(rand.New(rand.NewSource(1)), records, tt.trending, "category_page_id", nil, 10, "index", now)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("TrendingEvents() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
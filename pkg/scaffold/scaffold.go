@@ -0,0 +1,415 @@
+// Package scaffold derives a starter scenario from the records of an index: search terms drawn from
+// the records names and categories, weighted filters from the facet values, and personas built from
+// facet clusters.
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/scenario"
+)
+
+const (
+	// hierarchySeparator separates the levels of the hierarchical facet values (e.g. "Men > Shoes").
+	hierarchySeparator = " > "
+	// minWordLength is the minimum length of the words of the records names proposed as search terms.
+	minWordLength = 3
+	// minPhraseRecords is the minimum number of records names a two words term must be found in.
+	minPhraseRecords = 2
+	// personaWeight is the weight of the facet value of the persona cluster.
+	personaWeight = 10
+	// personaTerms is the maximum number of search terms of a persona.
+	personaTerms = 3
+)
+
+// stopWords are the words of the records names never proposed as search terms.
+var stopWords = map[string]bool{
+	"and": true, "for": true, "from": true, "the": true, "with": true, "without": true, "our": true, "your": true,
+}
+
+// Options are the options of the scenario derived from the records.
+type Options struct {
+	// NameAttribute is the attribute of the records names.
+	NameAttribute string
+	// Facets are the attributes of the filters and the personas clusters.
+	Facets []string
+	// SearchTerms is the maximum number of search terms.
+	SearchTerms int
+	// Personas is the maximum number of personas.
+	Personas int
+	// FacetValues is the maximum number of values of a filter.
+	FacetValues int
+}
+
+// Attributes returns the top-level attributes of the records the scenario is derived from: the names
+// and the facets. The search terms are matched against them.
+func (opts Options) Attributes() []string {
+	attributes := []string{"objectID"}
+	seen := map[string]bool{"objectID": true}
+	for _, attribute := range append([]string{opts.NameAttribute}, opts.Facets...) {
+		attribute = strings.SplitN(attribute, ".", 2)[0]
+		if attribute != "" && !seen[attribute] {
+			seen[attribute] = true
+			attributes = append(attributes, attribute)
+		}
+	}
+	return attributes
+}
+
+// Records browses all the records of an index, with only the given attributes.
+func Records(index *search.Index, attributes []string) ([]map[string]interface{}, error) {
+	it, err := index.BrowseObjects(opt.AttributesToRetrieve(attributes...))
+	if err != nil {
+		return nil, err
+	}
+	records := make([]map[string]interface{}, 0)
+	for {
+		obj, err := it.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record, ok := obj.(map[string]interface{}); ok {
+			records = append(records, record)
+		}
+	}
+}
+
+// Facets returns the facet attributes of an index, from its attributesForFaceting setting.
+func Facets(index *search.Index) ([]string, error) {
+	settings, err := index.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	var facets []string
+	if settings.AttributesForFaceting != nil {
+		for _, attribute := range settings.AttributesForFaceting.Get() {
+			facets = append(facets, events.FacetAttribute(attribute))
+		}
+	}
+	return facets, nil
+}
+
+// Scaffold returns a starter scenario with the search terms and personas derived from the records.
+func Scaffold(records []map[string]interface{}, opts Options) (*scenario.Scenario, error) {
+	if len(records) == 0 {
+		return nil, errors.New("no records to derive the scenario from")
+	}
+
+	s := &scenario.Scenario{Version: scenario.Version}
+	terms := searchTerms(records, opts)
+	for _, term := range terms {
+		s.SearchTerms = append(s.SearchTerms, events.SearchTerm{
+			Term:    term,
+			Filters: termFilters(matchingRecords(records, term), opts),
+		})
+	}
+	s.Personas = personas(records, terms, opts)
+	return s, nil
+}
+
+// valueCount is the number of records with a facet value.
+type valueCount struct {
+	Value string
+	Count int
+}
+
+// countValues counts the records of each value of a facet, the most frequent values first.
+func countValues(records []map[string]interface{}, facet string) []valueCount {
+	counts := make(map[string]int)
+	for _, record := range records {
+		for _, value := range facetValues(record, facet) {
+			counts[value]++
+		}
+	}
+	return sortCounts(counts)
+}
+
+// facetValues returns the distinct values of a facet of a record.
+// For the hierarchical facets, only the deepest values are kept: the upper levels would always be
+// more frequent than the categories the records are actually in.
+func facetValues(record map[string]interface{}, facet string) []string {
+	depth := 0
+	for _, value := range events.AttributeValues(record, facet) {
+		if d := strings.Count(value, hierarchySeparator); d > depth {
+			depth = d
+		}
+	}
+	seen := make(map[string]bool)
+	var values []string
+	for _, value := range events.AttributeValues(record, facet) {
+		if value == "" || seen[value] || strings.Count(value, hierarchySeparator) != depth {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+	return values
+}
+
+func sortCounts(counts map[string]int) []valueCount {
+	sorted := make([]valueCount, 0, len(counts))
+	for value, count := range counts {
+		sorted = append(sorted, valueCount{Value: value, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+	return sorted
+}
+
+// searchTerms proposes the search terms: the last word of the records names (the noun, as in
+// "Black leather jacket"), the recurring last two words, and the leaf categories of the hierarchical
+// facets. The terms matching the most
+// records come first, as the first terms of the scenario are the most searched ones.
+func searchTerms(records []map[string]interface{}, opts Options) []string {
+	proposed := make(map[string]int)
+	for _, record := range records {
+		candidates := make(map[string]bool)
+		for _, name := range events.AttributeValues(record, opts.NameAttribute) {
+			words := nameWords(name)
+			if n := len(words); n > 0 {
+				candidates[words[n-1]] = true
+				if n > 1 {
+					candidates[words[n-2]+" "+words[n-1]] = true
+				}
+			}
+		}
+		for _, facet := range opts.Facets {
+			for _, value := range facetValues(record, facet) {
+				if strings.Contains(value, hierarchySeparator) {
+					levels := strings.Split(value, hierarchySeparator)
+					candidates[strings.ToLower(levels[len(levels)-1])] = true
+				}
+			}
+		}
+		for candidate := range candidates {
+			proposed[candidate]++
+		}
+	}
+
+	matches := make(map[string]int)
+	for term, count := range proposed {
+		if strings.Contains(term, " ") && count < minPhraseRecords {
+			continue
+		}
+		matches[term] = len(matchingRecords(records, term))
+	}
+
+	var terms []string
+	kept := make(map[string]bool)
+	for _, c := range sortCounts(matches) {
+		if len(terms) == opts.SearchTerms {
+			break
+		}
+		// "jacket" and "jackets" are the same term.
+		key := singular(c.Value)
+		if c.Count == 0 || kept[key] {
+			continue
+		}
+		kept[key] = true
+		terms = append(terms, c.Value)
+	}
+	return terms
+}
+
+// nameWords returns the lowercase words of a record name that can be searched.
+func nameWords(name string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		word = strings.Trim(word, "'")
+		if len([]rune(word)) < minWordLength || stopWords[word] {
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// singular returns the term without the plural marks of its words.
+func singular(term string) string {
+	words := strings.Fields(term)
+	for i, word := range words {
+		switch {
+		case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"),
+			strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+			words[i] = strings.TrimSuffix(word, "es")
+		case strings.HasSuffix(word, "ss"):
+		default:
+			words[i] = strings.TrimSuffix(word, "s")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// matchingRecords returns the records matching a search term.
+func matchingRecords(records []map[string]interface{}, term string) []map[string]interface{} {
+	var matching []map[string]interface{}
+	for _, record := range records {
+		if events.MatchQuery(record, term) {
+			matching = append(matching, record)
+		}
+	}
+	return matching
+}
+
+// termFilters returns the filters of a search term: the values of the first facet with several values
+// among the matching records, weighted by their number of records. A single facet is used so every
+// filter returns results.
+func termFilters(records []map[string]interface{}, opts Options) events.Filters {
+	for _, facet := range opts.Facets {
+		values := countValues(records, facet)
+		if len(values) < 2 {
+			continue
+		}
+		if len(values) > opts.FacetValues {
+			values = values[:opts.FacetValues]
+		}
+		return events.Filters{facet: weights(values)}
+	}
+	return nil
+}
+
+func weights(values []valueCount) map[string]int {
+	w := make(map[string]int, len(values))
+	for _, v := range values {
+		w[v.Value] = v.Count
+	}
+	return w
+}
+
+// personas returns a persona for each of the most frequent values of the cluster facet: the users
+// filtering on the value, and on the values of a second facet among the records of the cluster.
+func personas(records []map[string]interface{}, terms []string, opts Options) []*events.User {
+	facet, clusters := clusterFacet(records, opts)
+	if facet == "" {
+		return nil
+	}
+	if len(clusters) > opts.Personas {
+		clusters = clusters[:opts.Personas]
+	}
+
+	var users []*events.User
+	tokens := make(map[string]bool)
+	for _, cluster := range clusters {
+		var clusterRecords []map[string]interface{}
+		for _, record := range records {
+			for _, value := range facetValues(record, facet) {
+				if value == cluster.Value {
+					clusterRecords = append(clusterRecords, record)
+					break
+				}
+			}
+		}
+
+		user := &events.User{
+			Description: fmt.Sprintf("User interested in the %s %q", facet, cluster.Value),
+			Token:       uniqueToken(tokens, cluster.Value),
+			Terms:       personaSearchTerms(clusterRecords, terms),
+			Filters:     events.Filters{facet: {cluster.Value: personaWeight}},
+		}
+		// The second facet values are counted in the cluster, so they are always found with the cluster value.
+		if second, values := secondFacet(clusterRecords, facet, opts); second != "" {
+			user.Filters[second] = weights(values)
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// clusterFacet returns the facet whose values make the personas clusters: the facet with the fewest
+// values, but enough of them for the number of personas.
+func clusterFacet(records []map[string]interface{}, opts Options) (string, []valueCount) {
+	var best []valueCount
+	bestFacet := ""
+	for _, facet := range opts.Facets {
+		values := countValues(records, facet)
+		if len(values) < 2 {
+			continue
+		}
+		enough, bestEnough := len(values) >= opts.Personas, len(best) >= opts.Personas
+		if bestFacet == "" ||
+			(enough && !bestEnough) ||
+			(enough && bestEnough && len(values) < len(best)) ||
+			(!enough && !bestEnough && len(values) > len(best)) {
+			best, bestFacet = values, facet
+		}
+	}
+	return bestFacet, best
+}
+
+// secondFacet returns the facet the most specific to the records of a cluster: the facet whose most
+// frequent value is found in the largest share of them.
+func secondFacet(records []map[string]interface{}, clusterFacet string, opts Options) (string, []valueCount) {
+	var best []valueCount
+	bestFacet := ""
+	for _, facet := range opts.Facets {
+		if facet == clusterFacet {
+			continue
+		}
+		values := countValues(records, facet)
+		if len(values) == 0 {
+			continue
+		}
+		if len(values) > opts.FacetValues {
+			values = values[:opts.FacetValues]
+		}
+		if bestFacet == "" || values[0].Count > best[0].Count {
+			best, bestFacet = values, facet
+		}
+	}
+	return bestFacet, best
+}
+
+// personaSearchTerms returns the search terms matching the most records of a cluster.
+func personaSearchTerms(records []map[string]interface{}, terms []string) []string {
+	matches := make(map[string]int)
+	for _, term := range terms {
+		if n := len(matchingRecords(records, term)); n > 0 {
+			matches[term] = n
+		}
+	}
+	var personaTermsList []string
+	for _, c := range sortCounts(matches) {
+		if len(personaTermsList) == personaTerms {
+			break
+		}
+		personaTermsList = append(personaTermsList, c.Value)
+	}
+	return personaTermsList
+}
+
+// uniqueToken returns a user token derived from a facet value, not in the tokens already used.
+func uniqueToken(tokens map[string]bool, value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "-") {
+			b.WriteRune('-')
+		}
+	}
+	base := "persona-" + strings.TrimSuffix(b.String(), "-")
+	token := base
+	for i := 2; tokens[token]; i++ {
+		token = fmt.Sprintf("%s-%d", base, i)
+	}
+	tokens[token] = true
+	return token
+}
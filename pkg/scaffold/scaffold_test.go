@@ -0,0 +1,109 @@
+package scaffold
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+)
+
+func TestScaffold(t *testing.T) {
+	index, err := events.NewLocalIndex("test", "testdata/records.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := Scaffold(index.Records, Options{
+		NameAttribute: "name",
+		Facets:        []string{"category_page_id", "brand", "gender", "available_sizes"},
+		SearchTerms:   4,
+		Personas:      2,
+		FacetValues:   2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The dresses are all in the same category, of the same brand and for women: the sizes are used.
+	expectedTerms := []events.SearchTerm{
+		{Term: "jacket", Filters: events.Filters{"category_page_id": {"Men > Clothing > Jackets": 2, "Women > Clothing > Jackets": 1}}},
+		{Term: "dress", Filters: events.Filters{"available_sizes": {"S": 2, "M": 1}}},
+		{Term: "leather jacket", Filters: events.Filters{"brand": {"Levi's": 1, "Michael Kors": 1}}},
+		{Term: "sneakers", Filters: events.Filters{"category_page_id": {"Men > Shoes > Sneakers": 1, "Women > Shoes > Sneakers": 1}}},
+	}
+	if !reflect.DeepEqual(s.SearchTerms, expectedTerms) {
+		t.Errorf("unexpected search terms:\n got: %+v\nwant: %+v", s.SearchTerms, expectedTerms)
+	}
+
+	// The gender is the facet with the fewest values (but enough for 2 personas).
+	expectedPersonas := []*events.User{
+		{
+			Description: `User interested in the gender "women"`,
+			Token:       "persona-women",
+			Terms:       []string{"dress", "jacket", "sneakers"},
+			Filters:     events.Filters{"gender": {"women": 10}, "available_sizes": {"S": 3, "M": 2}},
+		},
+		{
+			Description: `User interested in the gender "men"`,
+			Token:       "persona-men",
+			Terms:       []string{"jacket", "leather jacket", "sneakers"},
+			Filters:     events.Filters{"gender": {"men": 10}, "category_page_id": {"Men > Clothing > Jackets": 2, "Men > Shoes > Sneakers": 1}},
+		},
+	}
+	if !reflect.DeepEqual(s.Personas, expectedPersonas) {
+		for _, p := range s.Personas {
+			t.Logf("got: %+v", *p)
+		}
+		t.Errorf("unexpected personas")
+	}
+}
+
+func TestScaffold_NoRecords(t *testing.T) {
+	if _, err := Scaffold(nil, Options{}); err == nil {
+		t.Errorf("expected an error without records")
+	}
+}
+
+func TestSingular(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{term: "jackets", want: "jacket"},
+		{term: "dresses", want: "dress"},
+		{term: "dress", want: "dress"},
+		{term: "watches", want: "watch"},
+		{term: "leather jackets", want: "leather jacket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			if got := singular(tt.term); got != tt.want {
+				t.Errorf("singular() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueToken(t *testing.T) {
+	tokens := make(map[string]bool)
+	for _, tt := range []struct {
+		value string
+		want  string
+	}{
+		{value: "Levi's", want: "persona-levi-s"},
+		{value: "Men > Shoes", want: "persona-men-shoes"},
+		{value: "men", want: "persona-men"},
+		{value: "Men", want: "persona-men-2"},
+	} {
+		if got := uniqueToken(tokens, tt.value); got != tt.want {
+			t.Errorf("uniqueToken(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestOptions_Attributes(t *testing.T) {
+	opts := Options{NameAttribute: "name", Facets: []string{"brand", "color.original_name", "color.hex", "name"}}
+	expected := []string{"objectID", "name", "brand", "color"}
+	if got := opts.Attributes(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Attributes() = %v, want %v", got, expected)
+	}
+}
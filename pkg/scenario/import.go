@@ -0,0 +1,88 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/recommend"
+)
+
+// Files are the loose scenario files, as used before the scenario format. Empty file names are skipped.
+type Files struct {
+	SearchTerms    string
+	UserTags       string
+	Personas       string
+	EventsNames    string
+	ABTestVariants string
+	Recommend      string
+}
+
+// Import returns a scenario with the content of the loose scenario files.
+func Import(files Files) (*Scenario, error) {
+	s := &Scenario{Version: Version}
+
+	if files.SearchTerms != "" {
+		searchTerms, err := events.NewSearchTerms(files.SearchTerms)
+		if err != nil {
+			return nil, fileError(files.SearchTerms, err)
+		}
+		s.SearchTerms = searchTerms.SearchTerms
+	}
+
+	if files.UserTags != "" {
+		data, err := ioutil.ReadFile(files.UserTags)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &s.UserTags); err != nil {
+			return nil, fileError(files.UserTags, err)
+		}
+	}
+
+	if files.Personas != "" {
+		personas, err := events.NewUsersFromFile(nil, files.Personas)
+		if err != nil {
+			return nil, fileError(files.Personas, err)
+		}
+		s.Personas = personas
+	}
+
+	if files.EventsNames != "" {
+		eventsNames, err := events.EventNamesFromFile(files.EventsNames)
+		if err != nil {
+			return nil, fileError(files.EventsNames, err)
+		}
+		s.EventsNames = eventsNames
+	}
+
+	if files.ABTestVariants != "" {
+		variants, err := events.LoadABTestVariants(files.ABTestVariants)
+		if err != nil {
+			return nil, fileError(files.ABTestVariants, err)
+		}
+		s.ABTest = &ABTest{Variants: variants}
+	}
+
+	if files.Recommend != "" {
+		r, err := recommend.LoadRecommendConfig(nil, files.Recommend)
+		if err != nil {
+			return nil, fileError(files.Recommend, err)
+		}
+		s.Recommend = r
+	}
+
+	return s, nil
+}
+
+// fileError prefixes the error with the file name, unless it already holds it.
+func fileError(fileName string, err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return err
+	}
+	return fmt.Errorf("%s: %v", fileName, err)
+}
@@ -0,0 +1,192 @@
+// Package scenario handles the scenario files: a single versioned file (YAML or JSON) holding
+// the search terms, tags, personas, events names, rates, A/B test, growth and recommend configuration.
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/recommend"
+)
+
+// Version is the version of the scenario format written by fig.
+const Version = 1
+
+type Scenario struct {
+	Version int `json:"version"`
+
+	SearchTerms []events.SearchTerm       `json:"search_terms,omitempty"`
+	UserTags    map[string]map[string]int `json:"user_tags,omitempty"`
+	Personas    []*events.User            `json:"personas,omitempty"`
+	EventsNames events.EventNames         `json:"events_names,omitempty"`
+	Rates       *Rates                    `json:"rates,omitempty"`
+	ABTest      *ABTest                   `json:"ab_test,omitempty"`
+	Growth      *events.Growth            `json:"growth,omitempty"`
+	Recommend   *recommend.Recommend      `json:"recommend,omitempty"`
+}
+
+// Rates are the rates of the events command. Each rate defined sets the flag of the same name.
+type Rates struct {
+	HitsPerPage        *int     `json:"hits_per_page,omitempty"`
+	ClickPosition      *int     `json:"average_click_position,omitempty"`
+	ClickThroughRate   *float64 `json:"click_through_rate,omitempty"`
+	ConversionRate     *float64 `json:"conversion_rate,omitempty"`
+	ViewRate           *float64 `json:"view_rate,omitempty"`
+	RefinementRate     *float64 `json:"refinement_rate,omitempty"`
+	PaginationRate     *float64 `json:"pagination_rate,omitempty"`
+	TypingRate         *float64 `json:"typing_rate,omitempty"`
+	TypoRate           *float64 `json:"typo_rate,omitempty"`
+	QueryVariationRate *float64 `json:"query_variation_rate,omitempty"`
+	QueryVariations    *string  `json:"query_variations,omitempty"`
+}
+
+// ABTest is the A/B test configuration of the events command.
+// The variants targets are set in the scenario, the other fields set the flags of the same name.
+type ABTest struct {
+	VariantID  *int                  `json:"variant_id,omitempty"`
+	VariantCTR *float64              `json:"variant_ctr,omitempty"`
+	VariantCVR *float64              `json:"variant_cvr,omitempty"`
+	Discover   *bool                 `json:"discover,omitempty"`
+	Variants   events.ABTestVariants `json:"variants,omitempty"`
+}
+
+// Load reads a scenario file: YAML for the `.yml` and `.yaml` files, JSON otherwise.
+func Load(fileName string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if IsYAML(fileName) {
+		data, err = YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fileName, err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	s := &Scenario{}
+	if err := dec.Decode(s); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	if s.Version == 0 {
+		return nil, fmt.Errorf("%s: missing scenario version", fileName)
+	}
+	if s.Version > Version {
+		return nil, fmt.Errorf("%s: scenario version %d is not supported (up to %d), please upgrade fig", fileName, s.Version, Version)
+	}
+	return s, nil
+}
+
+// Save writes the scenario to a file: YAML for the `.yml` and `.yaml` files, JSON otherwise.
+func (s *Scenario) Save(fileName string) error {
+	var buf bytes.Buffer
+	if err := s.Write(&buf, IsYAML(fileName)); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, buf.Bytes(), 0644)
+}
+
+// Write writes the scenario, in YAML or in JSON.
+func (s *Scenario) Write(w io.Writer, asYAML bool) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if asYAML {
+		data, err = jsonToYAML(data)
+		if err != nil {
+			return err
+		}
+	} else {
+		data = append(data, '\n')
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// IsYAML returns true for the `.yml` and `.yaml` files.
+func IsYAML(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// ApplyEvents sets the sections defined in the scenario to the events configuration.
+func (s *Scenario) ApplyEvents(cfg *events.Config) error {
+	if len(s.SearchTerms) > 0 {
+		searchTerms, err := events.NewSearchTermsFromList(s.SearchTerms)
+		if err != nil {
+			return err
+		}
+		cfg.SearchTerms = searchTerms
+	}
+	if len(s.UserTags) > 0 {
+		tagsCollection, err := events.NewTagsCollection(s.UserTags)
+		if err != nil {
+			return err
+		}
+		cfg.TagsCollection = tagsCollection
+	}
+	if len(s.Personas) > 0 {
+		cfg.PersonaUsers = s.Personas
+	}
+	if len(s.EventsNames) > 0 {
+		cfg.EventsNames = s.EventsNames
+	}
+	if s.ABTest != nil && len(s.ABTest.Variants) > 0 {
+		cfg.ABTest.Variants = s.ABTest.Variants
+	}
+	if s.Growth != nil {
+		if err := s.Growth.Init(); err != nil {
+			return err
+		}
+		cfg.Growth = s.Growth
+	}
+	return nil
+}
+
+// Flags returns the values of the events command flags set by the scenario (rates and A/B test), by flag name.
+func (s *Scenario) Flags() map[string]string {
+	flags := make(map[string]string)
+	setInt := func(name string, v *int) {
+		if v != nil {
+			flags[name] = strconv.Itoa(*v)
+		}
+	}
+	setFloat := func(name string, v *float64) {
+		if v != nil {
+			flags[name] = strconv.FormatFloat(*v, 'f', -1, 64)
+		}
+	}
+	if r := s.Rates; r != nil {
+		setInt("hits-per-page", r.HitsPerPage)
+		setInt("average-click-position", r.ClickPosition)
+		setFloat("click-through-rate", r.ClickThroughRate)
+		setFloat("conversion-rate", r.ConversionRate)
+		setFloat("view-rate", r.ViewRate)
+		setFloat("refinement-rate", r.RefinementRate)
+		setFloat("pagination-rate", r.PaginationRate)
+		setFloat("typing-rate", r.TypingRate)
+		setFloat("typo-rate", r.TypoRate)
+		setFloat("query-variation-rate", r.QueryVariationRate)
+		if r.QueryVariations != nil {
+			flags["query-variations"] = *r.QueryVariations
+		}
+	}
+	if t := s.ABTest; t != nil {
+		setInt("ab-test-variant-id", t.VariantID)
+		setFloat("ab-test-variant-ctr", t.VariantCTR)
+		setFloat("ab-test-variant-cvr", t.VariantCVR)
+		if t.Discover != nil {
+			flags["ab-test-discover"] = strconv.FormatBool(*t.Discover)
+		}
+	}
+	return flags
+}
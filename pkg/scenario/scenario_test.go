@@ -0,0 +1,117 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+)
+
+func TestLoad(t *testing.T) {
+	s, err := Load("testdata/scenario.yml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := &events.Config{}
+	if err := s.ApplyEvents(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.SearchTerms.SearchTerms) != 2 || cfg.SearchTerms.SearchTerms[0].Filters["brand"]["Levi's"] != 1 {
+		t.Errorf("unexpected search terms: %+v", cfg.SearchTerms.SearchTerms)
+	}
+	if len(cfg.TagsCollection) != 1 || cfg.TagsCollection[0].Name != "platform" {
+		t.Errorf("unexpected tags: %+v", cfg.TagsCollection)
+	}
+	if len(cfg.PersonaUsers) != 1 || cfg.PersonaUsers[0].Token != "mrs-grim" {
+		t.Errorf("unexpected personas: %+v", cfg.PersonaUsers)
+	}
+	if len(cfg.ABTest.Variants) != 1 || cfg.ABTest.Variants[0].Index != "products_ranking" {
+		t.Errorf("unexpected A/B test variants: %+v", cfg.ABTest.Variants)
+	}
+	if cfg.Growth == nil || cfg.Growth.Model != events.GrowthSeasonal || cfg.Growth.Spike == nil || *cfg.Growth.Spike != 3 {
+		t.Errorf("unexpected growth: %+v", cfg.Growth)
+	}
+	if g := cfg.SearchTerms.SearchTerms[1].Growth; g == nil || g.Model != events.GrowthLogistic || g.MaxConversionRate != 12 {
+		t.Errorf("unexpected search term growth: %+v", g)
+	}
+	if s.Recommend == nil || s.Recommend.FacetName != "category_page_id" || len(s.Recommend.FBT["Women > Shoes"]) != 1 {
+		t.Errorf("unexpected recommend configuration: %+v", s.Recommend)
+	}
+
+	expectedFlags := map[string]string{
+		"click-through-rate":  "25.5",
+		"view-rate":           "40",
+		"query-variations":    "typo:1,casing:1",
+		"ab-test-variant-ctr": "3",
+		"ab-test-discover":    "true",
+	}
+	if flags := s.Flags(); !reflect.DeepEqual(flags, expectedFlags) {
+		t.Errorf("Scenario.Flags() = %v, want %v", flags, expectedFlags)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "missing version", content: "search_terms: []", wantErr: "missing scenario version"},
+		{name: "future version", content: "version: 99", wantErr: "scenario version 99 is not supported"},
+		{name: "unknown key", content: "version: 1\nsearch_term: []", wantErr: `unknown field "search_term"`},
+		{name: "unknown nested key", content: "version: 1\nrates:\n  ctr: 10", wantErr: `unknown field "ctr"`},
+		{name: "wrong type", content: "version: 1\nrates:\n  view_rate: high", wantErr: "cannot unmarshal string"},
+		{name: "unknown growth parameter", content: "version: 1\ngrowth:\n  model: linear\n  speed: 2", wantErr: `unknown field "speed"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "scenario.yml")
+			if err := os.WriteFile(fileName, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err := Load(fileName)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	s, err := Import(Files{
+		SearchTerms:    "../../searches.json",
+		UserTags:       "../../user-tags.json",
+		Personas:       "../../personas.json",
+		EventsNames:    "../../events-names.json",
+		ABTestVariants: "../../ab-test-variants.json",
+		Recommend:      "../../recommend.json",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Version != Version || len(s.SearchTerms) == 0 || len(s.UserTags) == 0 || len(s.Personas) == 0 ||
+		len(s.EventsNames) == 0 || s.ABTest == nil || s.Recommend == nil {
+		t.Fatalf("expected all the sections to be imported, got %+v", s)
+	}
+
+	// The imported scenario is the same once saved and loaded again, in YAML and in JSON.
+	for _, fileName := range []string{"scenario.yml", "scenario.json"} {
+		t.Run(fileName, func(t *testing.T) {
+			fileName = filepath.Join(t.TempDir(), fileName)
+			if err := s.Save(fileName); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			loaded, err := Load(fileName)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(loaded, s) {
+				t.Errorf("the loaded scenario differs from the saved one")
+			}
+		})
+	}
+}
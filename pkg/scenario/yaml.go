@@ -0,0 +1,113 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YAMLToJSON converts a YAML document to JSON, so it can be decoded with the json tags of the structs.
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	value, err := jsonValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// jsonValue converts the maps decoded from YAML (with interface{} keys) to JSON objects.
+func jsonValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			var err error
+			object[fmt.Sprintf("%v", key)], err = jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return object, nil
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			array[i], err = jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	}
+	return value, nil
+}
+
+// jsonToYAML converts a JSON document to YAML, keeping the order of the objects keys.
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := yamlValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+// yamlValue decodes the next JSON value, as an ordered yaml.MapSlice for the objects.
+func yamlValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		var value interface{}
+		switch t {
+		case '{':
+			object := yaml.MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				item, err := yamlValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				object = append(object, yaml.MapItem{Key: key, Value: item})
+			}
+			value = object
+		case '[':
+			array := make([]interface{}, 0)
+			for dec.More() {
+				item, err := yamlValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, item)
+			}
+			value = array
+		}
+		// Closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	}
+	return token, nil
+}
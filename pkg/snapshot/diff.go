@@ -0,0 +1,227 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// maxValueLength is the maximum length of the values printed in the changes.
+const maxValueLength = 60
+
+// ChangeType is the type of a change made by a restoration.
+type ChangeType string
+
+const (
+	Added    ChangeType = "+"
+	Removed  ChangeType = "-"
+	Modified ChangeType = "~"
+)
+
+// Change is a change made by a restoration to the current configuration.
+type Change struct {
+	Section string
+	Type    ChangeType
+	// Key is the setting name, the objectID of the rule or synonym, or the personalization strategy field.
+	Key string
+	// From and To are the JSON values of the settings and the personalization strategy fields.
+	From, To string
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Type == Modified && c.To != "":
+		return fmt.Sprintf("%s %s: %s → %s", c.Type, c.Key, shorten(c.From), shorten(c.To))
+	case c.Type == Added && c.To != "":
+		return fmt.Sprintf("%s %s: %s", c.Type, c.Key, shorten(c.To))
+	}
+	return fmt.Sprintf("%s %s", c.Type, c.Key)
+}
+
+func shorten(value string) string {
+	if r := []rune(value); len(r) > maxValueLength {
+		return string(r[:maxValueLength-1]) + "…"
+	}
+	return value
+}
+
+// Diff returns the changes the restoration of the snapshot would make to the current configuration.
+// Only the sections of the snapshot are compared. The settings are set, not replaced: the current
+// settings missing from the snapshot are kept, so they are not reported as removed.
+func (s *Snapshot) Diff(current *Snapshot) ([]Change, error) {
+	var changes []Change
+	if s.Settings != nil {
+		c, err := diffFields(SectionSettings, current.Settings, s.Settings, false)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	if s.Rules != nil {
+		from, to := make(map[string]interface{}), make(map[string]interface{})
+		for _, rule := range current.Rules {
+			from[rule.ObjectID] = rule
+		}
+		for _, rule := range s.Rules {
+			to[rule.ObjectID] = rule
+		}
+		c, err := diffObjects(SectionRules, from, to)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	if s.Synonyms != nil {
+		from, to := make(map[string]interface{}), make(map[string]interface{})
+		for _, synonym := range current.Synonyms {
+			from[synonym.ObjectID()] = synonym
+		}
+		for _, synonym := range s.Synonyms {
+			to[synonym.ObjectID()] = synonym
+		}
+		c, err := diffObjects(SectionSynonyms, from, to)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	if s.PersoStrategy != nil {
+		c, err := diffFields(SectionPersonalization, current.PersoStrategy, s.PersoStrategy, true)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	return changes, nil
+}
+
+// diffFields compares the JSON fields of two values. The fields missing from the target are only
+// reported as removed if they are replaced.
+func diffFields(section string, from, to interface{}, replaced bool) ([]Change, error) {
+	fromFields, err := jsonFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := jsonFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, key := range sortedFieldKeys(fromFields, toFields) {
+		fromValue, inFrom := fromFields[key]
+		toValue, inTo := toFields[key]
+		switch {
+		case inTo && !inFrom:
+			changes = append(changes, Change{Section: section, Type: Added, Key: key, To: toValue})
+		case inFrom && !inTo && replaced:
+			changes = append(changes, Change{Section: section, Type: Removed, Key: key, From: fromValue})
+		case inFrom && inTo && fromValue != toValue:
+			changes = append(changes, Change{Section: section, Type: Modified, Key: key, From: fromValue, To: toValue})
+		}
+	}
+	return changes, nil
+}
+
+// diffObjects compares two sets of objects (rules or synonyms) by objectID.
+func diffObjects(section string, from, to map[string]interface{}) ([]Change, error) {
+	var changes []Change
+	for _, key := range sortedObjectIDs(from, to) {
+		fromObject, inFrom := from[key]
+		toObject, inTo := to[key]
+		switch {
+		case inTo && !inFrom:
+			changes = append(changes, Change{Section: section, Type: Added, Key: key})
+		case inFrom && !inTo:
+			changes = append(changes, Change{Section: section, Type: Removed, Key: key})
+		default:
+			equal, err := jsonEqual(fromObject, toObject)
+			if err != nil {
+				return nil, err
+			}
+			if !equal {
+				changes = append(changes, Change{Section: section, Type: Modified, Key: key})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// jsonFields returns the compact JSON values of the fields of a value, by name.
+// The null fields are considered missing.
+func jsonFields(v interface{}) (map[string]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if value == nil {
+			continue
+		}
+		// Marshalling the decoded values gives a canonical form (sorted keys).
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = string(data)
+	}
+	return fields, nil
+}
+
+func jsonEqual(a, b interface{}) (bool, error) {
+	aFields, err := jsonFields(a)
+	if err != nil {
+		return false, err
+	}
+	bFields, err := jsonFields(b)
+	if err != nil {
+		return false, err
+	}
+	if len(aFields) != len(bFields) {
+		return false, nil
+	}
+	for key, value := range aFields {
+		if bFields[key] != value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sortedFieldKeys returns the keys of the fields of both settings, sorted.
+func sortedFieldKeys(a, b map[string]string) []string {
+	set := make(map[string]bool)
+	for key := range a {
+		set[key] = true
+	}
+	for key := range b {
+		set[key] = true
+	}
+	return sortedSet(set)
+}
+
+// sortedObjectIDs returns the objectIDs of both sets of objects, sorted.
+func sortedObjectIDs(a, b map[string]interface{}) []string {
+	set := make(map[string]bool)
+	for key := range a {
+		set[key] = true
+	}
+	for key := range b {
+		set[key] = true
+	}
+	return sortedSet(set)
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,309 @@
+// Package snapshot saves and restores the configuration of an index: its settings, rules, synonyms
+// and the personalization strategy of the application.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/personalization"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+// The files of a snapshot directory. The settings, rules and personalization strategy files have the
+// same format as the dashboard exports (and the `flagship_*.json` files).
+const (
+	ManifestFile      = "snapshot.json"
+	SettingsFile      = "settings.json"
+	RulesFile         = "rules.json"
+	SynonymsFile      = "synonyms.json"
+	PersoStrategyFile = "perso-strategy.json"
+)
+
+// The sections of a snapshot.
+const (
+	SectionSettings        = "settings"
+	SectionRules           = "rules"
+	SectionSynonyms        = "synonyms"
+	SectionPersonalization = "personalization"
+)
+
+// Sections are all the sections of a snapshot, in the order they are saved and restored.
+var Sections = []string{SectionSettings, SectionRules, SectionSynonyms, SectionPersonalization}
+
+// Manifest describes a snapshot.
+type Manifest struct {
+	Index   string    `json:"index"`
+	Region  string    `json:"region,omitempty"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// Snapshot is the configuration of an index. The nil sections are not saved nor restored.
+type Snapshot struct {
+	Manifest Manifest
+
+	Settings      *search.Settings
+	Rules         []search.Rule
+	Synonyms      []search.Synonym
+	PersoStrategy *personalization.Strategy
+}
+
+// Index is the index a snapshot is taken from and restored to.
+// It is implemented by `*search.Index`.
+type Index interface {
+	GetName() string
+	GetSettings(opts ...interface{}) (search.Settings, error)
+	SetSettings(settings search.Settings, opts ...interface{}) (search.UpdateTaskRes, error)
+	BrowseRules(opts ...interface{}) (*search.RuleIterator, error)
+	ReplaceAllRules(rules []search.Rule, opts ...interface{}) (search.UpdateTaskRes, error)
+	SaveRules(rules []search.Rule, opts ...interface{}) (search.UpdateTaskRes, error)
+	BrowseSynonyms(opts ...interface{}) (*search.SynonymIterator, error)
+	ReplaceAllSynonyms(synonyms []search.Synonym, opts ...interface{}) (search.UpdateTaskRes, error)
+	SaveSynonyms(synonyms []search.Synonym, opts ...interface{}) (search.UpdateTaskRes, error)
+	WaitTask(taskID int64, opts ...interface{}) error
+}
+
+// RestoreMode is how the rules and the synonyms of a snapshot are restored.
+type RestoreMode int
+
+const (
+	// Replace replaces all the rules and synonyms of the index by the ones of the snapshot.
+	Replace RestoreMode = iota
+	// Upsert adds the rules and synonyms of the snapshot, or updates the ones with the same objectID,
+	// and keeps the other rules and synonyms of the index.
+	Upsert
+)
+
+// PersonalizationClient gets and sets the personalization strategy of an application.
+// It is implemented by `*personalization.Client`.
+type PersonalizationClient interface {
+	GetPersonalizationStrategy(opts ...interface{}) (personalization.Strategy, error)
+	SetPersonalizationStrategy(strategy personalization.Strategy, opts ...interface{}) (personalization.SetPersonalizationStrategyRes, error)
+}
+
+// Take returns the snapshot of the given sections of an index.
+func Take(index Index, perso PersonalizationClient, region string, sections []string) (*Snapshot, error) {
+	s := &Snapshot{Manifest: Manifest{Index: index.GetName(), Region: region, SavedAt: time.Now().UTC()}}
+	for _, section := range sections {
+		switch section {
+		case SectionSettings:
+			settings, err := index.GetSettings()
+			if err != nil {
+				return nil, fmt.Errorf("error while getting the settings: %v", err)
+			}
+			s.Settings = &settings
+		case SectionRules:
+			rules, err := browseRules(index)
+			if err != nil {
+				return nil, fmt.Errorf("error while getting the rules: %v", err)
+			}
+			s.Rules = rules
+		case SectionSynonyms:
+			synonyms, err := browseSynonyms(index)
+			if err != nil {
+				return nil, fmt.Errorf("error while getting the synonyms: %v", err)
+			}
+			s.Synonyms = synonyms
+		case SectionPersonalization:
+			strategy, err := perso.GetPersonalizationStrategy()
+			if err != nil {
+				return nil, fmt.Errorf("error while getting the personalization strategy: %v", err)
+			}
+			s.PersoStrategy = &strategy
+		default:
+			return nil, fmt.Errorf("unknown snapshot section %q", section)
+		}
+	}
+	return s, nil
+}
+
+func browseRules(index Index) ([]search.Rule, error) {
+	it, err := index.BrowseRules()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]search.Rule, 0)
+	for {
+		rule, err := it.Next()
+		if err == io.EOF {
+			return rules, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+}
+
+func browseSynonyms(index Index) ([]search.Synonym, error) {
+	it, err := index.BrowseSynonyms()
+	if err != nil {
+		return nil, err
+	}
+	synonyms := make([]search.Synonym, 0)
+	for {
+		synonym, err := it.Next()
+		if err == io.EOF {
+			return synonyms, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		synonyms = append(synonyms, synonym)
+	}
+}
+
+// Restore applies the sections of the snapshot to an index: the settings are set, the rules and the
+// synonyms replace the existing ones or are upserted (see RestoreMode), and the personalization strategy
+// replaces the current one. It returns once the index tasks are done, so the index is ready to be searched.
+func (s *Snapshot) Restore(index Index, perso PersonalizationClient, mode RestoreMode) error {
+	saveRules, saveSynonyms := index.ReplaceAllRules, index.ReplaceAllSynonyms
+	if mode == Upsert {
+		saveRules, saveSynonyms = index.SaveRules, index.SaveSynonyms
+	}
+	var tasks []int64
+	if s.Settings != nil {
+		res, err := index.SetSettings(*s.Settings)
+		if err != nil {
+			return fmt.Errorf("error while restoring the settings: %v", err)
+		}
+		tasks = append(tasks, res.TaskID)
+	}
+	if s.Rules != nil {
+		res, err := saveRules(s.Rules)
+		if err != nil {
+			return fmt.Errorf("error while restoring the rules: %v", err)
+		}
+		tasks = append(tasks, res.TaskID)
+	}
+	if s.Synonyms != nil {
+		res, err := saveSynonyms(s.Synonyms)
+		if err != nil {
+			return fmt.Errorf("error while restoring the synonyms: %v", err)
+		}
+		tasks = append(tasks, res.TaskID)
+	}
+	if s.PersoStrategy != nil {
+		if _, err := perso.SetPersonalizationStrategy(*s.PersoStrategy); err != nil {
+			return fmt.Errorf("error while restoring the personalization strategy: %v", err)
+		}
+	}
+	for _, taskID := range tasks {
+		if err := index.WaitTask(taskID); err != nil {
+			return fmt.Errorf("error while waiting for the restore of the index: %v", err)
+		}
+	}
+	return nil
+}
+
+// Save writes the snapshot to a directory, one file per section.
+func (s *Snapshot) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	files := []struct {
+		name  string
+		value interface{}
+		saved bool
+	}{
+		{ManifestFile, s.Manifest, true},
+		{SettingsFile, s.Settings, s.Settings != nil},
+		{RulesFile, s.Rules, s.Rules != nil},
+		{SynonymsFile, s.Synonyms, s.Synonyms != nil},
+		{PersoStrategyFile, s.PersoStrategy, s.PersoStrategy != nil},
+	}
+	for _, f := range files {
+		if !f.saved {
+			continue
+		}
+		data, err := json.MarshalIndent(f.value, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads a snapshot directory. The sections without a file are left nil.
+func Load(dir string) (*Snapshot, error) {
+	s := &Snapshot{}
+	if err := readFile(filepath.Join(dir, ManifestFile), &s.Manifest); err != nil {
+		return nil, err
+	}
+	if err := LoadSections(s, existingFile(dir, SettingsFile), existingFile(dir, RulesFile),
+		existingFile(dir, SynonymsFile), existingFile(dir, PersoStrategyFile)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// existingFile returns the path of a file of the snapshot directory, or an empty string if it doesn't exist.
+func existingFile(dir, name string) string {
+	fileName := filepath.Join(dir, name)
+	if _, err := os.Stat(fileName); err != nil {
+		return ""
+	}
+	return fileName
+}
+
+// LoadSections reads the sections files into the snapshot. The sections with an empty file name are skipped.
+func LoadSections(s *Snapshot, settingsFile, rulesFile, synonymsFile, persoStrategyFile string) error {
+	var settings search.Settings
+	if ok, err := readSectionFile(settingsFile, &settings); err != nil {
+		return err
+	} else if ok {
+		s.Settings = &settings
+	}
+
+	var rules []search.Rule
+	if ok, err := readSectionFile(rulesFile, &rules); err != nil {
+		return err
+	} else if ok {
+		s.Rules = rules
+	}
+
+	var synonyms []map[string]interface{}
+	if ok, err := readSectionFile(synonymsFile, &synonyms); err != nil {
+		return err
+	} else if ok {
+		parsed, err := search.SearchSynonymsRes{Hits: synonyms}.Synonyms()
+		if err != nil {
+			return fmt.Errorf("%s: %v", synonymsFile, err)
+		}
+		s.Synonyms = append(make([]search.Synonym, 0, len(parsed)), parsed...)
+	}
+
+	var strategy personalization.Strategy
+	if ok, err := readSectionFile(persoStrategyFile, &strategy); err != nil {
+		return err
+	} else if ok {
+		s.PersoStrategy = &strategy
+	}
+	return nil
+}
+
+func readSectionFile(fileName string, v interface{}) (bool, error) {
+	if fileName == "" {
+		return false, nil
+	}
+	return true, readFile(fileName, v)
+}
+
+func readFile(fileName string, v interface{}) error {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", fileName, err)
+	}
+	return nil
+}
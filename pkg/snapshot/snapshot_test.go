@@ -0,0 +1,202 @@
+package snapshot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/personalization"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+// fakeIndex records the restored sections and the tasks waited for.
+type fakeIndex struct {
+	Index
+	settings *search.Settings
+	rules    []search.Rule
+	synonyms []search.Synonym
+	upserted bool
+	waited   []int64
+}
+
+func (i *fakeIndex) SetSettings(settings search.Settings, opts ...interface{}) (search.UpdateTaskRes, error) {
+	i.settings = &settings
+	return search.UpdateTaskRes{TaskID: 1}, nil
+}
+
+func (i *fakeIndex) ReplaceAllRules(rules []search.Rule, opts ...interface{}) (search.UpdateTaskRes, error) {
+	i.rules = rules
+	return search.UpdateTaskRes{TaskID: 2}, nil
+}
+
+func (i *fakeIndex) ReplaceAllSynonyms(synonyms []search.Synonym, opts ...interface{}) (search.UpdateTaskRes, error) {
+	i.synonyms = synonyms
+	return search.UpdateTaskRes{TaskID: 3}, nil
+}
+
+func (i *fakeIndex) SaveRules(rules []search.Rule, opts ...interface{}) (search.UpdateTaskRes, error) {
+	i.rules = rules
+	i.upserted = true
+	return search.UpdateTaskRes{TaskID: 4}, nil
+}
+
+func (i *fakeIndex) SaveSynonyms(synonyms []search.Synonym, opts ...interface{}) (search.UpdateTaskRes, error) {
+	i.synonyms = synonyms
+	i.upserted = true
+	return search.UpdateTaskRes{TaskID: 5}, nil
+}
+
+func (i *fakeIndex) WaitTask(taskID int64, opts ...interface{}) error {
+	i.waited = append(i.waited, taskID)
+	return nil
+}
+
+type fakePersonalization struct {
+	PersonalizationClient
+	strategy *personalization.Strategy
+	err      error
+}
+
+func (p *fakePersonalization) SetPersonalizationStrategy(strategy personalization.Strategy, opts ...interface{}) (personalization.SetPersonalizationStrategyRes, error) {
+	p.strategy = &strategy
+	return personalization.SetPersonalizationStrategyRes{}, p.err
+}
+
+func TestLoadSections_Flagship(t *testing.T) {
+	s := &Snapshot{}
+	err := LoadSections(s, "../../flagship_settings.json", "../../flagship_rules.json", "", "../../flagship_perso_strat.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Settings == nil || len(s.Rules) == 0 || s.PersoStrategy == nil || len(s.PersoStrategy.EventsScoring) == 0 {
+		t.Errorf("expected the settings, rules and personalization strategy to be loaded, got %+v", s)
+	}
+	if s.Synonyms != nil {
+		t.Errorf("expected no synonyms section, got %v", s.Synonyms)
+	}
+
+	if err := LoadSections(s, "missing.json", "", "", ""); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	s := &Snapshot{
+		Manifest: Manifest{Index: "products", Region: "eu"},
+		Settings: &search.Settings{HitsPerPage: opt.HitsPerPage(30), CustomRanking: opt.CustomRanking("desc(sales)")},
+		Rules:    []search.Rule{{ObjectID: "rule-1", Description: "Promote the jackets"}},
+		Synonyms: []search.Synonym{search.NewRegularSynonym("syn-1", "pants", "trousers")},
+	}
+	dir := t.TempDir()
+	if err := s.Save(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Manifest != s.Manifest {
+		t.Errorf("Manifest = %+v, want %+v", loaded.Manifest, s.Manifest)
+	}
+	if loaded.PersoStrategy != nil {
+		t.Errorf("expected no personalization strategy, got %+v", loaded.PersoStrategy)
+	}
+	changes, err := loaded.Diff(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes between the saved and loaded snapshots, got %v", changes)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := &Snapshot{
+		Settings: &search.Settings{HitsPerPage: opt.HitsPerPage(20), CustomRanking: opt.CustomRanking("desc(sales)")},
+		Rules: []search.Rule{
+			{ObjectID: "kept", Description: "same"},
+			{ObjectID: "modified", Description: "before"},
+			{ObjectID: "removed"},
+		},
+		Synonyms: []search.Synonym{search.NewRegularSynonym("syn-1", "pants", "trousers")},
+		PersoStrategy: &personalization.Strategy{
+			FacetsScoring:         []personalization.FacetsScoring{{FacetName: "brand", Score: 50}},
+			PersonalizationImpact: opt.PersonalizationImpact(50),
+		},
+	}
+	target := &Snapshot{
+		Settings: &search.Settings{HitsPerPage: opt.HitsPerPage(30), Distinct: opt.Distinct(true)},
+		Rules: []search.Rule{
+			{ObjectID: "added"},
+			{ObjectID: "kept", Description: "same"},
+			{ObjectID: "modified", Description: "after"},
+		},
+		Synonyms: []search.Synonym{search.NewRegularSynonym("syn-1", "pants", "trousers")},
+		PersoStrategy: &personalization.Strategy{
+			FacetsScoring:         []personalization.FacetsScoring{{FacetName: "brand", Score: 50}},
+			PersonalizationImpact: opt.PersonalizationImpact(80),
+		},
+	}
+
+	changes, err := target.Diff(current)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.Section+" "+change.String())
+	}
+	// The custom ranking is kept (the settings are not replaced), the synonyms are the same.
+	expected := []string{
+		"settings + distinct: 1",
+		"settings ~ hitsPerPage: 20 → 30",
+		"rules + added",
+		"rules ~ modified",
+		"rules - removed",
+		"personalization ~ personalizationImpact: 50 → 80",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Diff() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestRestore(t *testing.T) {
+	s := &Snapshot{
+		Settings:      &search.Settings{HitsPerPage: opt.HitsPerPage(30)},
+		Rules:         []search.Rule{},
+		PersoStrategy: &personalization.Strategy{PersonalizationImpact: opt.PersonalizationImpact(80)},
+	}
+
+	index := &fakeIndex{}
+	perso := &fakePersonalization{}
+	if err := s.Restore(index, perso, Replace); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if index.settings == nil || index.rules == nil || perso.strategy == nil {
+		t.Errorf("expected the settings, rules and personalization strategy to be restored")
+	}
+	if index.synonyms != nil {
+		t.Errorf("expected the synonyms to be left untouched, got %v", index.synonyms)
+	}
+	if !reflect.DeepEqual(index.waited, []int64{1, 2}) || index.upserted {
+		t.Errorf("expected the settings and rules tasks to be waited for, got %v", index.waited)
+	}
+
+	// The rules and synonyms can be upserted instead, keeping the other ones of the index.
+	s.Synonyms = []search.Synonym{search.NewRegularSynonym("syn-1", "pants", "trousers")}
+	index = &fakeIndex{}
+	if err := s.Restore(index, perso, Upsert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !index.upserted || index.rules == nil || index.synonyms == nil || !reflect.DeepEqual(index.waited, []int64{1, 4, 5}) {
+		t.Errorf("expected the rules and synonyms to be upserted, got %+v", index)
+	}
+
+	// The errors of the personalization API are not ignored.
+	perso = &fakePersonalization{err: errors.New("forbidden")}
+	if err := s.Restore(&fakeIndex{}, perso, Replace); err == nil || !strings.Contains(err.Error(), "forbidden") {
+		t.Errorf("Restore() error = %v, want the personalization error", err)
+	}
+}
@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// NewRand returns a new source of randomness for the given seed.
+// A seed of 0 means a random seed (non reproducible run).
+func NewRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
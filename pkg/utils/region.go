@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/region"
+)
+
+// apiRegions are the regions of the hosts of the Insights and Personalization APIs, by region:
+// in Europe, the Insights API is in `de` and the Personalization API in `eu`.
+var apiRegions = map[string]struct {
+	insights        region.Region
+	personalization region.Region
+}{
+	"us": {region.US, region.US},
+	"eu": {region.DE, region.EU},
+	"de": {region.DE, region.EU},
+}
+
+// APIRegions returns the regions of the Insights and Personalization APIs for a region (us, eu or de).
+func APIRegions(name string) (insights, personalization region.Region, err error) {
+	regions, ok := apiRegions[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown region %q: us, eu or de", name)
+	}
+	return regions.insights, regions.personalization, nil
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/region"
+)
+
+func TestAPIRegions(t *testing.T) {
+	tests := []struct {
+		name            string
+		insights        region.Region
+		personalization region.Region
+		wantErr         bool
+	}{
+		{name: "us", insights: region.US, personalization: region.US},
+		{name: "eu", insights: region.DE, personalization: region.EU},
+		{name: "de", insights: region.DE, personalization: region.EU},
+		{name: "fr", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insights, personalization, err := APIRegions(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("APIRegions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if insights != tt.insights || personalization != tt.personalization {
+				t.Errorf("APIRegions() = %q, %q, want %q, %q", insights, personalization, tt.insights, tt.personalization)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package validate
+
+import (
+	"fmt"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+)
+
+// Index is the index the search terms and filters are checked against:
+// the Algolia index or an offline records dump.
+type Index interface {
+	events.Searcher
+	// CheckFilterAttribute returns an error if the attribute can't be used in the filters.
+	CheckFilterAttribute(attribute string) error
+}
+
+// AlgoliaIndex is an Algolia index, whose filter attributes must be in the attributesForFaceting setting.
+type AlgoliaIndex struct {
+	*search.Index
+	facets map[string]bool
+}
+
+// NewAlgoliaIndex returns an AlgoliaIndex, fetching its settings.
+func NewAlgoliaIndex(index *search.Index) (*AlgoliaIndex, error) {
+	settings, err := index.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	i := &AlgoliaIndex{Index: index, facets: make(map[string]bool)}
+	if settings.AttributesForFaceting != nil {
+		for _, attribute := range settings.AttributesForFaceting.Get() {
+			i.facets[events.FacetAttribute(attribute)] = true
+		}
+	}
+	return i, nil
+}
+
+func (i *AlgoliaIndex) CheckFilterAttribute(attribute string) error {
+	if !i.facets[attribute] {
+		return fmt.Errorf("attribute %q is not in the attributesForFaceting of the index", attribute)
+	}
+	return nil
+}
+
+// LocalIndex is an offline records dump, whose filter attributes must be found in the records.
+type LocalIndex struct {
+	*events.LocalIndex
+}
+
+func (i *LocalIndex) CheckFilterAttribute(attribute string) error {
+	if !i.HasAttribute(attribute) {
+		return fmt.Errorf("attribute %q is not found in the records", attribute)
+	}
+	return nil
+}
+
+// ValidateIndex checks the search terms and filters of the validated files against an index:
+// the filter attributes must be usable, and each search term (alone and with each filter value)
+// should return results.
+func (r *Report) ValidateIndex(index Index) error {
+	checkedAttributes := make(map[string]error)
+	for _, tf := range r.filters {
+		for _, filter := range tf.Filters {
+			err, ok := checkedAttributes[filter.Attribute]
+			if !ok {
+				err = index.CheckFilterAttribute(filter.Attribute)
+				checkedAttributes[filter.Attribute] = err
+			}
+			if err != nil {
+				r.add(tf.File, filter.Line, SeverityError, "%v", err)
+			}
+		}
+
+		for _, term := range tf.Terms {
+			nbHits, err := countHits(index, term, "")
+			if err != nil {
+				return err
+			}
+			if tf.NoResults {
+				if nbHits > 0 {
+					r.add(tf.File, tf.Line, SeverityWarning, "search term %q is marked with no_results but returns %d hits", term, nbHits)
+				}
+				continue
+			}
+			if nbHits == 0 {
+				r.add(tf.File, tf.Line, SeverityWarning, "search term %q returns no results", term)
+				continue
+			}
+
+			for _, filter := range tf.Filters {
+				if checkedAttributes[filter.Attribute] != nil {
+					continue
+				}
+				nbHits, err := countHits(index, term, filter.String())
+				if err != nil {
+					return err
+				}
+				if nbHits == 0 {
+					r.add(tf.File, filter.Line, SeverityWarning, "search term %q with filter %s returns no results", term, filter)
+				}
+			}
+		}
+	}
+	r.sort()
+	return nil
+}
+
+// countHits returns the number of hits of a search, without recording it in the analytics.
+func countHits(index Index, query string, filters string) (int, error) {
+	opts := []interface{}{opt.HitsPerPage(0), opt.Analytics(false)}
+	if filters != "" {
+		opts = append(opts, opt.Filters(filters))
+	}
+	res, err := index.Search(query, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("search %q (filters: %q): %v", query, filters, err)
+	}
+	return res.NbHits, nil
+}
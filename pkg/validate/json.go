@@ -0,0 +1,152 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type nodeKind int
+
+const (
+	objectNode nodeKind = iota
+	arrayNode
+	stringNode
+	numberNode
+	boolNode
+	nullNode
+)
+
+func (k nodeKind) String() string {
+	return [...]string{"an object", "an array", "a string", "a number", "a boolean", "null"}[k]
+}
+
+// node is a parsed JSON value, with the line where it is defined to report the issues.
+type node struct {
+	kind nodeKind
+	line int
+
+	// Object
+	keys     []string
+	fields   map[string]*node
+	keyLines map[string]int
+
+	// Array
+	items []*node
+
+	// Scalars
+	str    string
+	number json.Number
+	b      bool
+}
+
+// lineIndex converts the offsets of a file to line numbers.
+type lineIndex []int
+
+func newLineIndex(data []byte) lineIndex {
+	var index lineIndex
+	for i, c := range data {
+		if c == '\n' {
+			index = append(index, i)
+		}
+	}
+	return index
+}
+
+func (l lineIndex) line(offset int64) int {
+	return sort.Search(len(l), func(i int) bool { return int64(l[i]) >= offset }) + 1
+}
+
+// parseJSON parses a JSON document, keeping the lines of the values and of the object keys.
+func parseJSON(data []byte) (*node, error) {
+	lines := newLineIndex(data)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := parseNode(dec, lines)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, &jsonError{line: lines.line(syntaxErr.Offset), err: err}
+		}
+		return nil, &jsonError{line: lines.line(dec.InputOffset()), err: err}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, &jsonError{line: lines.line(dec.InputOffset()), err: errors.New("unexpected data after the JSON value")}
+	}
+	return root, nil
+}
+
+// jsonError is a JSON syntax error, with its line.
+type jsonError struct {
+	line int
+	err  error
+}
+
+func (e *jsonError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+func parseNode(dec *json.Decoder, lines lineIndex) (*node, error) {
+	token, err := dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	n := &node{line: lines.line(dec.InputOffset())}
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			n.kind = objectNode
+			n.fields = make(map[string]*node)
+			n.keyLines = make(map[string]int)
+			for dec.More() {
+				keyToken, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := keyToken.(string)
+				keyLine := lines.line(dec.InputOffset())
+				value, err := parseNode(dec, lines)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := n.fields[key]; !ok {
+					n.keys = append(n.keys, key)
+				}
+				n.fields[key] = value
+				n.keyLines[key] = keyLine
+			}
+		case '[':
+			n.kind = arrayNode
+			for dec.More() {
+				item, err := parseNode(dec, lines)
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, item)
+			}
+		}
+		// Closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.kind = stringNode
+		n.str = t
+	case json.Number:
+		n.kind = numberNode
+		n.number = t
+	case bool:
+		n.kind = boolNode
+		n.b = t
+	case nil:
+		n.kind = nullNode
+	}
+	return n, nil
+}
@@ -0,0 +1,171 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/scenario"
+)
+
+// validateScenario checks a scenario file: its sections are checked like the matching files.
+func validateScenario(r *Report, file string, root *node) {
+	c := checker{r: r, file: file}
+	if !c.kind(root, objectNode, "the scenario") {
+		return
+	}
+	c.keys(root, "the scenario", []string{"version", "search_terms", "user_tags", "personas", "events_names",
+		"rates", "ab_test", "growth", "recommend"}, "version")
+	for _, key := range root.keys {
+		value := root.fields[key]
+		switch key {
+		case "version":
+			c.integer(value, "the scenario version", 1, scenario.Version)
+		case "search_terms":
+			validateSearchTerms(r, file, value)
+		case "user_tags":
+			validateUserTags(r, file, value)
+		case "personas":
+			validatePersonas(r, file, value)
+		case "events_names":
+			validateEventsNames(r, file, value)
+		case "rates":
+			validateRates(c, value)
+		case "ab_test":
+			validateABTest(c, value)
+		case "growth":
+			validateGrowth(c, value, "growth")
+		case "recommend":
+			validateRecommend(c, value)
+		}
+	}
+}
+
+func validateRates(c checker, n *node) {
+	if !c.kind(n, objectNode, "rates") {
+		return
+	}
+	rates := []string{"click_through_rate", "conversion_rate", "view_rate", "refinement_rate", "pagination_rate",
+		"typing_rate", "typo_rate", "query_variation_rate"}
+	c.keys(n, "rates", append([]string{"hits_per_page", "average_click_position", "query_variations"}, rates...))
+	for _, key := range n.keys {
+		value := n.fields[key]
+		switch {
+		case key == "hits_per_page":
+			c.integer(value, "rates hits_per_page", 1, 1000)
+		case key == "average_click_position":
+			c.integer(value, "rates average_click_position", 1, math.MaxInt32)
+		case key == "query_variations":
+			if c.kind(value, stringNode, "rates query_variations") {
+				if _, err := events.ParseQueryVariationsWeights(value.str); err != nil {
+					c.errorf(value.line, "invalid rates query_variations: %v", err)
+				}
+			}
+		case contains(rates, key):
+			c.number(value, "rates "+key, 0, 100)
+		}
+	}
+}
+
+func validateABTest(c checker, n *node) {
+	if !c.kind(n, objectNode, "ab_test") {
+		return
+	}
+	c.keys(n, "ab_test", []string{"variant_id", "variant_ctr", "variant_cvr", "discover", "variants"})
+	for _, key := range n.keys {
+		value := n.fields[key]
+		switch key {
+		case "variant_id":
+			c.integer(value, "ab_test variant_id", 1, math.MaxInt32)
+		case "variant_ctr", "variant_cvr":
+			c.number(value, "ab_test "+key, 0, 100)
+		case "discover":
+			c.kind(value, boolNode, "ab_test discover")
+		case "variants":
+			validateABTestVariants(c.r, c.file, value)
+		}
+	}
+}
+
+func validateRecommend(c checker, n *node) {
+	if !c.kind(n, objectNode, "recommend") {
+		return
+	}
+	c.keys(n, "recommend", []string{"facet_name", "fbt", "multipliers", "related", "trending"})
+	for _, key := range n.keys {
+		value := n.fields[key]
+		switch key {
+		case "facet_name":
+			c.kind(value, stringNode, "recommend facet_name")
+		case "fbt":
+			if c.kind(value, objectNode, "recommend fbt") {
+				for _, category := range value.keys {
+					c.stringList(value.fields[category], fmt.Sprintf("recommend fbt %q", category))
+				}
+			}
+		case "multipliers":
+			if c.kind(value, objectNode, "recommend multipliers") {
+				for _, category := range value.keys {
+					c.number(value.fields[category], fmt.Sprintf("multiplier of %q", category), 0, math.MaxFloat64)
+				}
+			}
+		case "related":
+			validateRelated(c, value)
+		case "trending":
+			validateTrending(c, value)
+		}
+	}
+}
+
+func validateRelated(c checker, n *node) {
+	if !c.kind(n, objectNode, "recommend related") {
+		return
+	}
+	c.keys(n, "recommend related", []string{"attributes", "sessions_per_object", "clicks_per_session", "conversion_rate",
+		"neighbors", "random_click_rate"}, "attributes")
+	for _, key := range n.keys {
+		value := n.fields[key]
+		switch key {
+		case "attributes":
+			if !c.kind(value, objectNode, "recommend related attributes") {
+				continue
+			}
+			if len(value.keys) == 0 {
+				c.errorf(value.line, "recommend related attributes must have at least one attribute")
+			}
+			for _, attribute := range value.keys {
+				c.number(value.fields[attribute], fmt.Sprintf("weight of %q in recommend related attributes", attribute), 0, math.MaxFloat64)
+			}
+		case "sessions_per_object":
+			c.integer(value, "recommend related "+key, 0, math.MaxInt32)
+		case "clicks_per_session", "neighbors":
+			c.integer(value, "recommend related "+key, 1, math.MaxInt32)
+		case "conversion_rate", "random_click_rate":
+			c.number(value, "recommend related "+key, 0, 100)
+		}
+	}
+}
+
+func validateTrending(c checker, n *node) {
+	if !c.kind(n, objectNode, "recommend trending") {
+		return
+	}
+	c.keys(n, "recommend trending", []string{"object_ids", "facets", "days", "boost"})
+	for _, key := range n.keys {
+		value := n.fields[key]
+		switch key {
+		case "object_ids":
+			c.stringList(value, "recommend trending object_ids")
+		case "facets":
+			if c.kind(value, objectNode, "recommend trending facets") {
+				for _, facet := range value.keys {
+					c.stringList(value.fields[facet], fmt.Sprintf("recommend trending facet %q", facet))
+				}
+			}
+		case "days":
+			c.integer(value, "recommend trending days", 1, math.MaxInt32)
+		case "boost":
+			c.number(value, "recommend trending boost", 1, math.MaxFloat64)
+		}
+	}
+}
@@ -0,0 +1,518 @@
+// Package validate checks the scenario files of the events command: their shape, their weights
+// and, against an index, their search terms and filters.
+package validate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+	"github.com/algolia/fake-insights-generator/pkg/scenario"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found in a scenario file.
+type Issue struct {
+	File     string
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+}
+
+// Report is the list of issues found in the scenario files.
+type Report struct {
+	Issues []Issue
+
+	// filters are the search terms and filters found in the files, checked against the index.
+	filters []termFilters
+}
+
+// termFilters are the filters used with the search terms of a file.
+type termFilters struct {
+	File      string
+	Line      int
+	Terms     []string
+	NoResults bool
+	Filters   []filterValue
+}
+
+type filterValue struct {
+	Line      int
+	Attribute string
+	Value     string
+}
+
+func (f filterValue) String() string {
+	return fmt.Sprintf("%s:\"%s\"", f.Attribute, f.Value)
+}
+
+// Errors returns the number of issues with the error severity.
+func (r *Report) Errors() int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+// Warnings returns the number of issues with the warning severity.
+func (r *Report) Warnings() int {
+	return len(r.Issues) - r.Errors()
+}
+
+func (r *Report) add(file string, line int, severity Severity, format string, a ...interface{}) {
+	r.Issues = append(r.Issues, Issue{File: file, Line: line, Severity: severity, Message: fmt.Sprintf(format, a...)})
+}
+
+// sort orders the issues by file and line.
+func (r *Report) sort() {
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		if r.Issues[i].File != r.Issues[j].File {
+			return r.Issues[i].File < r.Issues[j].File
+		}
+		return r.Issues[i].Line < r.Issues[j].Line
+	})
+}
+
+// Files are the scenario files to validate. Empty file names are skipped.
+type Files struct {
+	SearchTerms    string
+	UserTags       string
+	Personas       string
+	EventsNames    string
+	ABTestVariants string
+	// Scenario is a scenario file, YAML or JSON, holding the sections of the files above.
+	Scenario string
+}
+
+// ValidateFiles checks the shape of the scenario files: unknown keys, wrong types and bad weights.
+func ValidateFiles(files Files) *Report {
+	r := &Report{}
+	validators := []struct {
+		file     string
+		validate func(r *Report, file string, root *node)
+	}{
+		{files.SearchTerms, validateSearchTerms},
+		{files.UserTags, validateUserTags},
+		{files.Personas, validatePersonas},
+		{files.EventsNames, validateEventsNames},
+		{files.ABTestVariants, validateABTestVariants},
+		{files.Scenario, validateScenario},
+	}
+	for _, v := range validators {
+		if v.file == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(v.file)
+		if err != nil {
+			r.add(v.file, 0, SeverityError, "%v", err)
+			continue
+		}
+
+		// The YAML scenarios are checked once converted to JSON: the lines of their issues are unknown.
+		yaml := v.file == files.Scenario && scenario.IsYAML(v.file)
+		if yaml {
+			data, err = scenario.YAMLToJSON(data)
+			if err != nil {
+				r.add(v.file, 0, SeverityError, "invalid YAML: %v", err)
+				continue
+			}
+		}
+		issues, filters := len(r.Issues), len(r.filters)
+
+		root, err := parseJSON(data)
+		if err != nil {
+			line := 0
+			if jsonErr, ok := err.(*jsonError); ok {
+				line, err = jsonErr.line, jsonErr.err
+			}
+			r.add(v.file, line, SeverityError, "invalid JSON: %v", err)
+			continue
+		}
+		v.validate(r, v.file, root)
+		if yaml {
+			r.clearLines(issues, filters)
+		}
+	}
+	r.sort()
+	return r
+}
+
+// clearLines removes the lines of the issues and filters found from the given indexes on.
+func (r *Report) clearLines(issues int, filters int) {
+	for i := issues; i < len(r.Issues); i++ {
+		r.Issues[i].Line = 0
+	}
+	for i := filters; i < len(r.filters); i++ {
+		r.filters[i].Line = 0
+		for j := range r.filters[i].Filters {
+			r.filters[i].Filters[j].Line = 0
+		}
+	}
+}
+
+// checker reports the issues of a file.
+type checker struct {
+	r    *Report
+	file string
+}
+
+func (c checker) errorf(line int, format string, a ...interface{}) {
+	c.r.add(c.file, line, SeverityError, format, a...)
+}
+
+func (c checker) warnf(line int, format string, a ...interface{}) {
+	c.r.add(c.file, line, SeverityWarning, format, a...)
+}
+
+// kind checks the kind of a value.
+func (c checker) kind(n *node, kind nodeKind, what string) bool {
+	if n.kind != kind {
+		c.errorf(n.line, "%s must be %s, not %s", what, kind, n.kind)
+		return false
+	}
+	return true
+}
+
+// keys checks that an object only has known keys, and the required ones.
+func (c checker) keys(n *node, what string, known []string, required ...string) {
+	for _, key := range n.keys {
+		if !contains(known, key) {
+			c.errorf(n.keyLines[key], "unknown key %q in %s (expected one of: %s)", key, what, strings.Join(known, ", "))
+		}
+	}
+	for _, key := range required {
+		if _, ok := n.fields[key]; !ok {
+			c.errorf(n.line, "missing key %q in %s", key, what)
+		}
+	}
+}
+
+// number checks a number value between min and max.
+func (c checker) number(n *node, what string, min float64, max float64) (float64, bool) {
+	if !c.kind(n, numberNode, what) {
+		return 0, false
+	}
+	v, err := n.number.Float64()
+	if err != nil || v < min || v > max {
+		if max == math.MaxFloat64 {
+			c.errorf(n.line, "%s must be at least %v, not %s", what, min, n.number)
+		} else {
+			c.errorf(n.line, "%s must be between %v and %v, not %s", what, min, max, n.number)
+		}
+		return 0, false
+	}
+	return v, true
+}
+
+// integer checks an integer value between min and max.
+func (c checker) integer(n *node, what string, min int64, max int64) bool {
+	if !c.kind(n, numberNode, what) {
+		return false
+	}
+	v, err := n.number.Int64()
+	if err != nil {
+		c.errorf(n.line, "%s must be an integer, not %s", what, n.number)
+		return false
+	}
+	if v < min || v > max {
+		c.errorf(n.line, "%s must be between %d and %d, not %d", what, min, max, v)
+		return false
+	}
+	return true
+}
+
+// stringList checks an array of strings.
+func (c checker) stringList(n *node, what string) []string {
+	if !c.kind(n, arrayNode, what) {
+		return nil
+	}
+	var values []string
+	for i, item := range n.items {
+		if c.kind(item, stringNode, fmt.Sprintf("%s #%d", what, i+1)) {
+			values = append(values, item.str)
+		}
+	}
+	return values
+}
+
+// weights checks an object of weighted values, as picked with weightedrand:
+// the weights must be positive integers.
+func (c checker) weights(n *node, what string) {
+	if !c.kind(n, objectNode, what) {
+		return
+	}
+	if len(n.keys) == 0 {
+		c.errorf(n.line, "%s must have at least one value", what)
+	}
+	for _, key := range n.keys {
+		c.integer(n.fields[key], fmt.Sprintf("weight of %q in %s", key, what), 1, math.MaxInt32)
+	}
+}
+
+// filters checks the filters of a search term or a persona, and returns them.
+func (c checker) filters(n *node, what string) []filterValue {
+	if !c.kind(n, objectNode, what) {
+		return nil
+	}
+	var values []filterValue
+	for _, attribute := range n.keys {
+		attributeValues := n.fields[attribute]
+		if strings.TrimSpace(attribute) == "" || strings.ContainsAny(attribute, ":\"") {
+			c.errorf(n.keyLines[attribute], "invalid filter attribute %q in %s", attribute, what)
+			continue
+		}
+		c.weights(attributeValues, fmt.Sprintf("filter %q", attribute))
+		for _, value := range attributeValues.keys {
+			values = append(values, filterValue{Line: attributeValues.keyLines[value], Attribute: attribute, Value: value})
+		}
+	}
+	return values
+}
+
+func validateSearchTerms(r *Report, file string, root *node) {
+	c := checker{r: r, file: file}
+	if !c.kind(root, arrayNode, "the search terms") {
+		return
+	}
+	if len(root.items) == 0 {
+		c.errorf(root.line, "no search terms")
+	}
+	for i, item := range root.items {
+		what := fmt.Sprintf("search term #%d", i+1)
+		if !c.kind(item, objectNode, what) {
+			continue
+		}
+		c.keys(item, what, []string{"term", "click_through_rate", "conversion_rate", "click_position", "synonyms", "filters", "no_results", "variations", "growth"}, "term")
+
+		tf := termFilters{File: file, Line: item.line}
+		rates := make(map[string]float64)
+		for _, key := range item.keys {
+			value := item.fields[key]
+			switch key {
+			case "term":
+				if c.kind(value, stringNode, what+" term") {
+					tf.Terms = append(tf.Terms, value.str)
+				}
+			case "click_through_rate", "conversion_rate":
+				if rate, ok := c.number(value, what+" "+key, 0, 100); ok {
+					rates[key] = rate
+				}
+			case "click_position":
+				c.integer(value, what+" click_position", 1, math.MaxInt32)
+			case "synonyms":
+				tf.Terms = append(tf.Terms, c.stringList(value, what+" synonyms")...)
+			case "filters":
+				tf.Filters = c.filters(value, what+" filters")
+			case "no_results":
+				if c.kind(value, boolNode, what+" no_results") {
+					tf.NoResults = value.b
+				}
+			case "variations":
+				validateQueryVariations(c, value, what+" variations")
+			case "growth":
+				validateGrowth(c, value, what+" growth")
+			}
+		}
+		// The conversions only happen after a click.
+		if ctr, ok := rates["click_through_rate"]; ok && rates["conversion_rate"] > ctr {
+			c.warnf(item.fields["conversion_rate"].line, "%s conversion_rate is above its click_through_rate: it converts at %v%% at most", what, ctr)
+		}
+		r.filters = append(r.filters, tf)
+	}
+}
+
+func validateQueryVariations(c checker, n *node, what string) {
+	if !c.kind(n, objectNode, what) {
+		return
+	}
+	weights := []string{"typo", "plural", "casing", "word_order"}
+	c.keys(n, what, append([]string{"rate"}, weights...))
+	rate := 0.0
+	totalWeight := int64(0)
+	for _, key := range n.keys {
+		value := n.fields[key]
+		if key == "rate" {
+			rate, _ = c.number(value, what+" rate", 0, 100)
+		} else if contains(weights, key) && c.integer(value, what+" "+key, 0, math.MaxInt32) {
+			weight, _ := value.number.Int64()
+			totalWeight += weight
+		}
+	}
+	if rate > 0 && totalWeight == 0 {
+		c.errorf(n.line, "%s has a rate but no weights", what)
+	}
+}
+
+func validateGrowth(c checker, n *node, what string) {
+	if !c.kind(n, objectNode, what) {
+		return
+	}
+	strs := []string{"model", "origin", "peak", "holiday"}
+	numbers := []string{"rate", "target", "midpoint", "steepness", "amplitude", "spike", "spike_days",
+		"max", "max_click_through_rate", "max_conversion_rate"}
+	c.keys(n, what, append(strs, numbers...), "model")
+
+	// The fields are checked, then the growth itself: its model, dates and caps.
+	errors := c.r.Errors()
+	g := &events.Growth{}
+	values := make(map[string]float64)
+	for _, key := range n.keys {
+		value := n.fields[key]
+		if contains(strs, key) {
+			if c.kind(value, stringNode, what+" "+key) {
+				switch key {
+				case "model":
+					g.Model = value.str
+				case "origin":
+					g.Origin = value.str
+				case "peak":
+					g.Peak = value.str
+				case "holiday":
+					g.Holiday = value.str
+				}
+			}
+		} else if contains(numbers, key) && c.kind(value, numberNode, what+" "+key) {
+			values[key], _ = value.number.Float64()
+		}
+	}
+	if c.r.Errors() > errors {
+		return
+	}
+	optional := func(key string) *float64 {
+		if value, ok := values[key]; ok {
+			return &value
+		}
+		return nil
+	}
+	g.Rate, g.Target, g.Midpoint, g.Spike = optional("rate"), optional("target"), optional("midpoint"), optional("spike")
+	g.Steepness, g.Amplitude, g.SpikeDays = values["steepness"], values["amplitude"], values["spike_days"]
+	g.Max, g.MaxClickThroughRate, g.MaxConversionRate = values["max"], values["max_click_through_rate"], values["max_conversion_rate"]
+	if err := g.Init(); err != nil {
+		c.errorf(n.line, "invalid %s: %v", what, err)
+	}
+}
+
+func validateUserTags(r *Report, file string, root *node) {
+	c := checker{r: r, file: file}
+	if !c.kind(root, objectNode, "the user tags") {
+		return
+	}
+	for _, name := range root.keys {
+		c.weights(root.fields[name], fmt.Sprintf("tags %q", name))
+	}
+}
+
+func validatePersonas(r *Report, file string, root *node) {
+	c := checker{r: r, file: file}
+	if !c.kind(root, arrayNode, "the personas") {
+		return
+	}
+	tokens := make(map[string]bool)
+	for i, item := range root.items {
+		what := fmt.Sprintf("persona #%d", i+1)
+		if !c.kind(item, objectNode, what) {
+			continue
+		}
+		c.keys(item, what, []string{"description", "token", "tags", "terms", "filters"}, "token")
+
+		tf := termFilters{File: file, Line: item.line}
+		for _, key := range item.keys {
+			value := item.fields[key]
+			switch key {
+			case "description":
+				c.kind(value, stringNode, what+" description")
+			case "token":
+				if !c.kind(value, stringNode, what+" token") {
+					continue
+				}
+				if value.str == "" {
+					c.errorf(value.line, "%s token must not be empty", what)
+				} else if tokens[value.str] {
+					c.errorf(value.line, "%s token %q is already used by another persona", what, value.str)
+				}
+				tokens[value.str] = true
+			case "tags":
+				c.stringList(value, what+" tags")
+			case "terms":
+				tf.Terms = c.stringList(value, what+" terms")
+			case "filters":
+				tf.Filters = c.filters(value, what+" filters")
+			}
+		}
+		if len(tf.Terms) > 0 {
+			r.filters = append(r.filters, tf)
+		}
+	}
+}
+
+func validateEventsNames(r *Report, file string, root *node) {
+	c := checker{r: r, file: file}
+	if !c.kind(root, objectNode, "the events names") {
+		return
+	}
+	c.keys(root, "the events names", []string{"click", "conversion", "view"}, "click", "conversion")
+	for _, eventType := range root.keys {
+		c.weights(root.fields[eventType], fmt.Sprintf("%s events names", eventType))
+	}
+	if _, ok := root.fields["view"]; !ok {
+		c.warnf(root.line, "no view events names: no view events will be sent (see the --view-rate flag)")
+	}
+}
+
+func validateABTestVariants(r *Report, file string, root *node) {
+	c := checker{r: r, file: file}
+	if !c.kind(root, arrayNode, "the A/B test variants") {
+		return
+	}
+	for i, item := range root.items {
+		what := fmt.Sprintf("variant #%d", i+1)
+		if !c.kind(item, objectNode, what) {
+			continue
+		}
+		c.keys(item, what, []string{"id", "index", "click_through_rate", "conversion_rate", "click_position"})
+		_, hasID := item.fields["id"]
+		_, hasIndex := item.fields["index"]
+		if !hasID && !hasIndex {
+			c.errorf(item.line, "%s must have an id or an index", what)
+		}
+		for _, key := range item.keys {
+			value := item.fields[key]
+			switch key {
+			case "id", "click_position":
+				c.integer(value, what+" "+key, 1, math.MaxInt32)
+			case "index":
+				c.kind(value, stringNode, what+" index")
+			case "click_through_rate", "conversion_rate":
+				c.number(value, what+" "+key, 0, 100)
+			}
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/algolia/fake-insights-generator/pkg/events"
+)
+
+func issuesStrings(r *Report) []string {
+	var issues []string
+	for _, issue := range r.Issues {
+		issues = append(issues, string(issue.Severity)+": "+issue.String())
+	}
+	return issues
+}
+
+func TestValidate(t *testing.T) {
+	r := ValidateFiles(Files{
+		SearchTerms: "testdata/searches.json",
+		UserTags:    "testdata/user-tags.json",
+		Personas:    "testdata/personas.json",
+		EventsNames: "testdata/events-names.json",
+	})
+	records, err := events.NewLocalIndex("products", "testdata/records.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.ValidateIndex(&LocalIndex{LocalIndex: records}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`warning: testdata/events-names.json:1: no view events names: no view events will be sent (see the --view-rate flag)`,
+		`error: testdata/events-names.json:6: weight of "PLP: Add to cart" in conversion events names must be between 1 and 2147483647, not -1`,
+		`error: testdata/events-names.json:8: unknown key "purchase" in the events names (expected one of: click, conversion, view)`,
+		`error: testdata/personas.json:13: persona #2 token "mrs-grim" is already used by another persona`,
+		`error: testdata/personas.json:15: unknown key "filter" in persona #2 (expected one of: description, token, tags, terms, filters)`,
+		`error: testdata/searches.json:4: search term #1 click_through_rate must be between 0 and 100, not 120`,
+		`warning: testdata/searches.json:8: search term "jacket" with filter brand:"Gucci" returns no results`,
+		`error: testdata/searches.json:11: attribute "colour" is not found in the records`,
+		`error: testdata/searches.json:17: unknown key "synonym" in search term #2 (expected one of: term, click_through_rate, conversion_rate, click_position, synonyms, filters, no_results, variations, growth)`,
+		`error: testdata/searches.json:20: weight of "women" in filter "gender" must be between 1 and 2147483647, not 0`,
+		`warning: testdata/searches.json:24: search term "sandals" returns no results`,
+		`error: testdata/searches.json:26: search term #3 variations has a rate but no weights`,
+		`warning: testdata/searches.json:37: search term #5 conversion_rate is above its click_through_rate: it converts at 5% at most`,
+		`error: testdata/searches.json:45: invalid search term #7 growth: unknown growth model: "exponential" (linear, logistic, step or seasonal)`,
+		`error: testdata/user-tags.json:3: invalid JSON: invalid character ',' looking for beginning of value`,
+	}
+	issues := issuesStrings(r)
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %d:\n%s", len(expected), len(issues), strings.Join(issues, "\n"))
+	}
+	for i := range expected {
+		if issues[i] != expected[i] {
+			t.Errorf("issue #%d:\nexpected %s\ngot      %s", i, expected[i], issues[i])
+		}
+	}
+	if r.Errors() != 11 || r.Warnings() != 4 {
+		t.Errorf("expected 11 errors and 4 warnings, got %d errors and %d warnings", r.Errors(), r.Warnings())
+	}
+}
+
+func TestValidate_SampleFiles(t *testing.T) {
+	r := ValidateFiles(Files{
+		SearchTerms:    "../../searches.json",
+		UserTags:       "../../user-tags.json",
+		Personas:       "../../personas.json",
+		EventsNames:    "../../events-names.json",
+		ABTestVariants: "../../ab-test-variants.json",
+	})
+	if len(r.Issues) > 0 {
+		t.Errorf("expected no issues in the sample files, got:\n%s", strings.Join(issuesStrings(r), "\n"))
+	}
+}
+
+func TestValidate_Scenario(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected []string
+	}{
+		{
+			name: "JSON",
+			file: "testdata/scenario.json",
+			expected: []string{
+				`error: testdata/scenario.json:8: rates hits_per_page must be between 1 and 1000, not 0`,
+				`error: testdata/scenario.json:9: invalid rates query_variations: unknown query variation: accent`,
+				`error: testdata/scenario.json:12: variant #1 must have an id or an index`,
+				`error: testdata/scenario.json:15: unknown key "facetName" in recommend (expected one of: facet_name, fbt, multipliers, related, trending)`,
+				`error: testdata/scenario.json:18: recommend related random_click_rate must be between 0 and 100, not 120`,
+				`error: testdata/scenario.json:22: recommend trending boost must be at least 1, not 0.5`,
+			},
+		},
+		{
+			// The lines of the YAML files are unknown.
+			name: "YAML",
+			file: "../scenario/testdata/scenario.yml",
+			expected: []string{
+				`warning: ../scenario/testdata/scenario.yml: no view events names: no view events will be sent (see the --view-rate flag)`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := issuesStrings(ValidateFiles(Files{Scenario: tt.file}))
+			if strings.Join(issues, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(tt.expected, "\n"), strings.Join(issues, "\n"))
+			}
+		})
+	}
+}